@@ -0,0 +1,566 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+	"github.com/traefik/lobicornis/v2/pkg/ghapp"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
+	"github.com/traefik/lobicornis/v2/pkg/notify"
+	"github.com/traefik/lobicornis/v2/pkg/repository"
+	"github.com/traefik/lobicornis/v2/pkg/search"
+	"github.com/traefik/lobicornis/v2/pkg/tracing"
+	"github.com/xanzy/go-gitlab"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/oauth2"
+)
+
+// notifyTimeout the timeout of the requests to the notifiers.
+const notifyTimeout = 10 * time.Second
+
+// bot processes the pull requests of the repositories.
+type bot struct {
+	cfg    conf.Configuration
+	owners []*owner
+
+	// locks ensures that at most one pull request per repository is processed at a time.
+	locks *repoLocks
+
+	cache *repository.Cache
+
+	auditLog *audit.Log
+	notifier *notify.Notifier
+
+	board *dashboard
+
+	// transport the transport of the GitHub clients, shared by the bots of all the configurations (reloads).
+	transport http.RoundTripper
+
+	// repos the metadata of the repositories, fetched once per sweep.
+	repos *repoInfos
+}
+
+// owner the forge client and the configuration of an owner (user, organization, or group).
+type owner struct {
+	name    string
+	markers conf.Markers
+
+	forge  forge.Forge
+	finder search.Finder
+
+	// credentials gets the credentials used by git.
+	credentials func() (string, error)
+}
+
+// job the pull requests of a repository found by a sweep.
+type job struct {
+	owner     *owner
+	fullName  string
+	issues    []*github.Issue
+	ffResults map[string][]*github.Issue
+}
+
+// newBot creates a bot.
+// The transport of the GitHub clients must be shared by the bots: the secondary rate limits must be respected by all the concurrent workers.
+func newBot(ctx context.Context, cfg conf.Configuration, transport http.RoundTripper) (*bot, error) {
+	b := &bot{
+		cfg:       cfg,
+		locks:     newRepoLocks(),
+		cache:     repository.NewCache(cfg.Git.Cache),
+		auditLog:  audit.New(cfg.Audit),
+		board:     newDashboard(),
+		repos:     newRepoInfos(),
+		transport: transport,
+	}
+
+	notifier, err := notify.New(&http.Client{Transport: tracing.Transport(http.DefaultTransport), Timeout: notifyTimeout}, cfg.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	b.notifier = notifier
+
+	var app *ghapp.App
+	if cfg.Forge == conf.ForgeGitHub && cfg.Github.App.ID != 0 {
+		app, err = newGitHubApp(cfg.Github)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ownerCfg := range cfg.GetOwners() {
+		o, err := newOwner(ctx, cfg, ownerCfg, app, transport)
+		if err != nil {
+			return nil, fmt.Errorf("owner %s: %w", ownerCfg.Name, err)
+		}
+
+		b.owners = append(b.owners, o)
+	}
+
+	return b, nil
+}
+
+func newOwner(ctx context.Context, cfg conf.Configuration, ownerCfg conf.Owner, app *ghapp.App, transport http.RoundTripper) (*owner, error) {
+	o := &owner{
+		name:    ownerCfg.Name,
+		markers: ownerCfg.Markers,
+	}
+
+	switch cfg.Forge {
+	case conf.ForgeGitLab:
+		token := firstNonEmpty(ownerCfg.Token, cfg.GitLab.Token)
+
+		client, err := gitlab.NewClient(token, gitlab.WithBaseURL(cfg.GitLab.URL), gitlab.WithHTTPClient(newHTTPClient()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the GitLab client: %w", err)
+		}
+
+		o.forge = forge.NewGitLab(client)
+		// GitLab accepts the API tokens for git over HTTPS with the oauth2 user.
+		o.credentials = staticCredentials("oauth2:" + token)
+
+	case conf.ForgeGitea:
+		token := firstNonEmpty(ownerCfg.Token, cfg.Gitea.Token)
+
+		frg, err := forge.NewGitea(newHTTPClient(), cfg.Gitea.URL, token)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the Gitea client: %w", err)
+		}
+
+		o.forge = frg
+		o.credentials = staticCredentials(token)
+
+	default:
+		var ts oauth2.TokenSource
+		o.credentials = staticCredentials(firstNonEmpty(ownerCfg.Token, cfg.Github.Token))
+
+		switch {
+		case ownerCfg.Token != "":
+			ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ownerCfg.Token})
+		case app != nil:
+			ts = app.TokenSource(ctx, ownerCfg.Name)
+			o.credentials = appCredentials(ts)
+		case cfg.Github.Token != "":
+			ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.Github.Token})
+		}
+
+		o.forge = forge.NewGitHub(newGitHubClient(ctx, transport, ts, cfg.Github.URL), ownerCfg.Name)
+	}
+
+	o.finder = search.New(o.forge, o.markers, cfg.Retry, cfg.Priority)
+
+	return o, nil
+}
+
+// run processes all the repositories of all the owners, using a bounded pool of workers.
+// The failure of an owner doesn't prevent the processing of the other owners.
+func (b *bot) run(ctx context.Context) (err error) {
+	defer metrics.Since(metrics.SweepDuration, time.Now())
+
+	// the root span of the sweep.
+	ctx, span := tracing.Start(ctx, "run")
+	defer func() { tracing.End(span, err) }()
+
+	var jobs []job
+	var failures []string
+
+	// the repositories without pull request to merge are not found by the sweep.
+	metrics.QueueDepth.Reset()
+
+	// the webhook deliveries use the metadata of the last sweep.
+	b.repos.reset()
+
+	for _, o := range b.owners {
+		ffResults, results, err := o.searchPulls(ctx)
+		if err != nil {
+			log.Error().Err(err).Str("owner", o.name).Msg("unable to search pull requests")
+			failures = append(failures, o.name)
+			continue
+		}
+
+		b.board.retain(o.name, results)
+
+		for fullName, issues := range results {
+			metrics.QueueDepth.WithLabelValues(fullName).Set(float64(len(issues)))
+
+			jobs = append(jobs, job{owner: o, fullName: fullName, issues: issues, ffResults: ffResults})
+		}
+	}
+
+	queue := make(chan job)
+
+	var wg sync.WaitGroup
+	for i := 0; i < b.cfg.Extra.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range queue {
+				if !b.locks.tryLock(j.fullName) {
+					log.Info().Str("repo", j.fullName).Msg("A pull request of the repository is already in process.")
+					continue
+				}
+
+				b.processRepository(ctx, j.owner, j.fullName, j.issues, j.ffResults, nil)
+
+				b.locks.unlock(j.fullName)
+			}
+		}()
+	}
+
+	err = dispatch(ctx, queue, jobs)
+
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("unable to search the pull requests of the owners: %s", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
+
+func dispatch(ctx context.Context, queue chan<- job, jobs []job) error {
+	defer close(queue)
+
+	for _, j := range jobs {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case queue <- j:
+		}
+	}
+
+	return nil
+}
+
+// searchPulls searches the PRs with the FF merge method and the PRs that need to be merged.
+func (o *owner) searchPulls(ctx context.Context, parameters ...search.Parameter) (map[string][]*github.Issue, map[string][]*github.Issue, error) {
+	// search PRs with the FF merge method.
+	ffParameters := append([]search.Parameter{
+		search.WithLabels(o.markers.MergeMethodPrefix + conf.MergeMethodFastForward),
+		search.WithExcludedLabels(o.markers.NoMerge, o.markers.NeedMerge),
+	}, parameters...)
+
+	ffResults, err := o.finder.Search(ctx, o.name, ffParameters...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// search NeedMerge
+	needMergeParameters := append([]search.Parameter{
+		search.WithLabels(o.markers.NeedMerge),
+		search.WithExcludedLabels(o.markers.NeedHumanMerge, o.markers.NoMerge),
+	}, parameters...)
+
+	results, err := o.finder.Search(ctx, o.name, needMergeParameters...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ffResults, results, nil
+}
+
+// isSelected checks if a repository is selected by the filters (patterns and topics).
+// The archived repositories are always skipped.
+func (b *bot) isSelected(ctx context.Context, o *owner, fullName string) (bool, error) {
+	if !b.cfg.Filters.MatchName(fullName) {
+		return false, nil
+	}
+
+	repo, err := b.repos.get(ctx, o.forge, fullName)
+	if err != nil {
+		return false, fmt.Errorf("unable to get the repository: %w", err)
+	}
+
+	if repo.GetArchived() {
+		return false, nil
+	}
+
+	return b.cfg.Filters.MatchTopics(repo.Topics), nil
+}
+
+// getRepoConfig gets the configuration of a repository, including the configuration file of the repository.
+func (b *bot) getRepoConfig(ctx context.Context, o *owner, fullName string) (conf.RepoConfig, error) {
+	owner, name := splitFullName(fullName)
+
+	data, err := o.forge.GetFileContent(ctx, owner, name, conf.RepoConfigFile)
+	if errors.Is(err, forge.ErrNotFound) {
+		return b.cfg.GetRepoConfig(fullName, nil), nil
+	}
+	if err != nil {
+		return conf.RepoConfig{}, fmt.Errorf("unable to get %s: %w", conf.RepoConfigFile, err)
+	}
+
+	repoFile, err := conf.ParseRepoConfig(data)
+	if err != nil {
+		return conf.RepoConfig{}, fmt.Errorf("invalid %s: %w", conf.RepoConfigFile, err)
+	}
+
+	config := b.cfg.GetRepoConfig(fullName, repoFile)
+
+	err = b.cfg.ValidateRepoConfig(config)
+	if err != nil {
+		return conf.RepoConfig{}, fmt.Errorf("%s: %w", conf.RepoConfigFile, err)
+	}
+
+	return config, nil
+}
+
+// getOwner gets the owner of a repository.
+func (b *bot) getOwner(fullName string) (*owner, bool) {
+	name := fullName
+	if index := strings.LastIndex(fullName, "/"); index >= 0 {
+		name = fullName[:index]
+	}
+
+	for _, o := range b.owners {
+		if strings.EqualFold(o.name, name) {
+			return o, true
+		}
+	}
+
+	return nil, false
+}
+
+// processRepository processes the current pull request of a repository, or its merge queue.
+// If prNumbers is not empty, the current pull request is processed only if it's one of them.
+func (b *bot) processRepository(ctx context.Context, o *owner, fullName string, issues []*github.Issue, ffResults map[string][]*github.Issue, prNumbers []int) {
+	ctx, span := tracing.Start(ctx, "processRepository", attribute.String("repo", fullName))
+	defer span.End()
+
+	logger := log.With().Str("owner", o.name).Str("repo", fullName).Logger()
+
+	selected, err := b.isSelected(ctx, o, fullName)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to check the filters")
+		return
+	}
+
+	if !selected {
+		logger.Debug().Msg("The repository is not selected by the filters.")
+		b.board.remove(fullName)
+		return
+	}
+
+	if ff, ok := ffResults[fullName]; ok {
+		logger.Info().Msgf("Waiting for the merge of pull request with the label: %s", o.markers.MergeMethodPrefix+conf.MergeMethodFastForward)
+		b.board.update(fullName, issues, o.markers, false, ff[0], search.ReasonFastForward)
+		return
+	}
+
+	repoConfig, err := b.getRepoConfig(ctx, o, fullName)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to get the configuration of the repository")
+		return
+	}
+
+	token, err := o.credentials()
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to get the git credentials")
+		return
+	}
+
+	err = o.finder.Sort(logger.WithContext(ctx), fullName, issues)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to sort the pull requests")
+		return
+	}
+
+	if repoConfig.GetMergeQueue() {
+		b.board.update(fullName, issues, o.markers, true, nil, "")
+
+		repo := repository.New(o.forge, fullName, token, o.markers, b.cfg.Retry, b.cfg.Git, repoConfig, b.cfg.Extra, b.cache, b.auditLog, b.notifier)
+
+		err := repo.ProcessQueue(logger.WithContext(ctx), issues)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to process the merge queue")
+		}
+
+		b.board.setOutcome(fullName, 0, err, token)
+
+		return
+	}
+
+	issue, reason, err := o.finder.GetCurrentPull(logger.WithContext(ctx), issues)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to get the current pull request")
+		return
+	}
+
+	b.board.update(fullName, issues, o.markers, false, issue, reason)
+
+	if issue == nil {
+		logger.Debug().Msg("Nothing to merge.")
+		return
+	}
+
+	loggerIssue := logger.With().Int("pr", issue.GetNumber()).Logger()
+
+	if len(prNumbers) > 0 && !containsNumber(prNumbers, issue.GetNumber()) {
+		loggerIssue.Debug().Msgf("The event doesn't concern the current pull request: %v", prNumbers)
+		return
+	}
+
+	repo := repository.New(o.forge, fullName, token, o.markers, b.cfg.Retry, b.cfg.Git, repoConfig, b.cfg.Extra, b.cache, b.auditLog, b.notifier)
+
+	err = repo.Process(loggerIssue.WithContext(ctx), issue.GetNumber())
+	if err != nil {
+		loggerIssue.Error().Err(err).Msg("Failed to process")
+	}
+
+	b.board.setOutcome(fullName, issue.GetNumber(), err, token)
+}
+
+func splitFullName(fullName string) (string, string) {
+	index := strings.LastIndex(fullName, "/")
+
+	return fullName[:index], fullName[index+1:]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func staticCredentials(token string) func() (string, error) {
+	return func() (string, error) {
+		return token, nil
+	}
+}
+
+// appCredentials the installation tokens of a GitHub App are used by git with the x-access-token user.
+func appCredentials(ts oauth2.TokenSource) func() (string, error) {
+	return func() (string, error) {
+		token, err := ts.Token()
+		if err != nil {
+			return "", err
+		}
+
+		return "x-access-token:" + token.AccessToken, nil
+	}
+}
+
+// repoInfos the metadata of the repositories (archived, topics), kept until the next sweep.
+type repoInfos struct {
+	mu    sync.Mutex
+	repos map[string]*github.Repository
+}
+
+func newRepoInfos() *repoInfos {
+	return &repoInfos{repos: make(map[string]*github.Repository)}
+}
+
+// get gets the metadata of a repository, from the forge only if the repository is unknown since the start of the sweep.
+func (c *repoInfos) get(ctx context.Context, frg forge.Forge, fullName string) (*github.Repository, error) {
+	if c != nil {
+		c.mu.Lock()
+		repo, ok := c.repos[strings.ToLower(fullName)]
+		c.mu.Unlock()
+
+		if ok {
+			return repo, nil
+		}
+	}
+
+	owner, name := splitFullName(fullName)
+
+	repo, err := frg.GetRepository(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		c.mu.Lock()
+		c.repos[strings.ToLower(fullName)] = repo
+		c.mu.Unlock()
+	}
+
+	return repo, nil
+}
+
+// reset forgets the metadata of the repositories.
+func (c *repoInfos) reset() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.repos = make(map[string]*github.Repository)
+	c.mu.Unlock()
+}
+
+// repoLocks a lock by repository.
+type repoLocks struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+func newRepoLocks() *repoLocks {
+	return &repoLocks{locks: make(map[string]chan struct{})}
+}
+
+// lock waits for the lock of a repository.
+func (l *repoLocks) lock(ctx context.Context, fullName string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case l.get(fullName) <- struct{}{}:
+		return nil
+	}
+}
+
+// tryLock acquires the lock of a repository, only if the lock is free.
+func (l *repoLocks) tryLock(fullName string) bool {
+	select {
+	case l.get(fullName) <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *repoLocks) unlock(fullName string) {
+	<-l.get(fullName)
+}
+
+func (l *repoLocks) get(fullName string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[fullName]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		l.locks[fullName] = lock
+	}
+
+	return lock
+}
+
+func containsNumber(values []int, value int) bool {
+	for _, val := range values {
+		if value == val {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+)
+
+func Test_repoLocks(t *testing.T) {
+	locks := newRepoLocks()
+
+	require.True(t, locks.tryLock("foo/bar"))
+
+	// the lock is by repository.
+	assert.False(t, locks.tryLock("foo/bar"))
+	assert.True(t, locks.tryLock("foo/baz"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := locks.lock(ctx, "foo/bar")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	locks.unlock("foo/bar")
+
+	err = locks.lock(context.Background(), "foo/bar")
+	require.NoError(t, err)
+
+	locks.unlock("foo/bar")
+	locks.unlock("foo/baz")
+
+	assert.True(t, locks.tryLock("foo/bar"))
+}
+
+func Test_bot_getOwner(t *testing.T) {
+	b := &bot{owners: []*owner{{name: "traefik"}, {name: "group/subgroup"}}}
+
+	testCases := []struct {
+		fullName string
+		expected string
+		ok       bool
+	}{
+		{fullName: "traefik/traefik", expected: "traefik", ok: true},
+		{fullName: "Traefik/traefik", expected: "traefik", ok: true},
+		{fullName: "group/subgroup/project", expected: "group/subgroup", ok: true},
+		{fullName: "group/project"},
+		{fullName: "ldez/traefik"},
+	}
+
+	for _, test := range testCases {
+		o, ok := b.getOwner(test.fullName)
+
+		require.Equal(t, test.ok, ok, test.fullName)
+
+		if test.ok {
+			assert.Equal(t, test.expected, o.name, test.fullName)
+		}
+	}
+}
+
+// repositoryForge counts the calls to the metadata of the repositories.
+type repositoryForge struct {
+	forge.Forge
+
+	calls *int
+}
+
+func (f repositoryForge) GetRepository(_ context.Context, owner, repo string) (*github.Repository, error) {
+	*f.calls++
+
+	return &github.Repository{FullName: github.String(owner + "/" + repo)}, nil
+}
+
+func Test_repoInfos(t *testing.T) {
+	var calls int
+	frg := repositoryForge{calls: &calls}
+
+	infos := newRepoInfos()
+
+	for _, fullName := range []string{"foo/bar", "Foo/Bar", "foo/baz"} {
+		repo, err := infos.get(context.Background(), frg, fullName)
+		require.NoError(t, err)
+		require.NotNil(t, repo)
+	}
+
+	assert.Equal(t, 2, calls)
+
+	// the next sweep.
+	infos.reset()
+
+	_, err := infos.get(context.Background(), frg, "foo/bar")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, calls)
+
+	// without cache.
+	var nilInfos *repoInfos
+
+	_, err = nilInfos.get(context.Background(), frg, "foo/bar")
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/ratelimit"
+	"github.com/traefik/lobicornis/v2/pkg/repository"
+)
+
+// Subcommands.
+const (
+	cmdValidate = "validate"
+	cmdExplain  = "explain"
+	cmdSchema   = "schema"
+	cmdAudit    = "audit"
+)
+
+// runValidate validates a configuration file, without any call to the forge.
+func runValidate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(cmdValidate, flag.ContinueOnError)
+	filename := fs.String("config", "./lobicornis.yml", "Path to the configuration file.")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	_, err = conf.Load(*filename, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(stdout, "%s: the configuration is valid.\n", *filename)
+
+	return err
+}
+
+// runSchema prints the JSON Schema of the configuration file, or of the repository configuration file.
+func runSchema(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(cmdSchema, flag.ContinueOnError)
+	repo := fs.Bool("repo", false, "The schema of the repository configuration file ("+conf.RepoConfigFile+").")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	generate := conf.Schema
+	if *repo {
+		generate = conf.RepoSchema
+	}
+
+	data, err := generate()
+	if err != nil {
+		return err
+	}
+
+	_, err = stdout.Write(data)
+
+	return err
+}
+
+// runExplain explains the decision of the bot for a pull request (owner/repo#123), without any change.
+func runExplain(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(cmdExplain, flag.ContinueOnError)
+	filename := fs.String("config", "./lobicornis.yml", "Path to the configuration file.")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: explain [-config file] owner/repo#123")
+	}
+
+	fullName, number, err := parsePullRequestRef(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := conf.Load(*filename, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := newBot(ctx, cfg, ratelimit.NewTransport(http.DefaultTransport, ratelimit.DefaultInterval))
+	if err != nil {
+		return err
+	}
+
+	o, ok := b.getOwner(fullName)
+	if !ok {
+		return fmt.Errorf("the owner of %s is not managed by the bot", fullName)
+	}
+
+	selected, err := b.isSelected(ctx, o, fullName)
+	if err != nil {
+		return err
+	}
+
+	repoConfig, err := b.getRepoConfig(ctx, o, fullName)
+	if err != nil {
+		return err
+	}
+
+	// the repository is only read: the credentials of git are not needed.
+	repo := repository.New(o.forge, fullName, "", o.markers, cfg.Retry, cfg.Git, repoConfig, conf.Extra{DryRun: true}, nil, nil, nil)
+
+	exp, err := repo.Explain(ctx, number)
+	if err != nil {
+		return err
+	}
+
+	if !selected {
+		exp.Decision = "ignored: the repository is not selected by the filters (or is archived)"
+	}
+
+	return displayExplanation(stdout, fullName, repoConfig, selected, exp)
+}
+
+func displayExplanation(w io.Writer, fullName string, repoConfig conf.RepoConfig, selected bool, exp *repository.Explanation) error {
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "%s#%d: %s\n\n", fullName, exp.PullRequest.GetNumber(), exp.PullRequest.GetTitle())
+
+	_, _ = fmt.Fprintf(&b, "  %-6s %-14s %s\n", status(selected), "filters", fullName)
+
+	for _, gate := range exp.Gates {
+		_, _ = fmt.Fprintf(&b, "  %-6s %-14s %s\n", status(gate.Passed), gate.Name, gate.Detail)
+	}
+
+	_, _ = fmt.Fprintf(&b, "\nConfiguration: mergeMethod=%s minReview=%d minLightReview=%d needMilestone=%t checkNeedUpToDate=%t forceNeedUpToDate=%t\n",
+		repoConfig.GetMergeMethod(), repoConfig.GetMinReview(), repoConfig.GetMinLightReview(), repoConfig.GetNeedMilestone(),
+		repoConfig.GetCheckNeedUpToDate(), repoConfig.GetForceNeedUpToDate())
+
+	_, _ = fmt.Fprintf(&b, "Decision: %s\n", exp.Decision)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func status(passed bool) string {
+	if passed {
+		return "[ok]"
+	}
+
+	return "[KO]"
+}
+
+// runAudit prints the records of the audit log (JSON Lines) matching the filters.
+func runAudit(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(cmdAudit, flag.ContinueOnError)
+	filename := fs.String("config", "./lobicornis.yml", "Path to the configuration file.")
+	file := fs.String("file", "", "Path to the audit log. (default: audit.file of the configuration file)")
+	repo := fs.String("repo", "", "The full name of a repository (owner/repo).")
+	pr := fs.Int("pr", 0, "The number of a pull request.")
+	since := fs.String("since", "", "The records written at or after this time (RFC 3339 or 2006-01-02).")
+	until := fs.String("until", "", "The records written before this time (RFC 3339 or 2006-01-02).")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	filter := audit.Filter{Repo: *repo, PR: *pr}
+
+	filter.Since, err = parseTime(*since)
+	if err != nil {
+		return fmt.Errorf("invalid since: %w", err)
+	}
+
+	filter.Until, err = parseTime(*until)
+	if err != nil {
+		return fmt.Errorf("invalid until: %w", err)
+	}
+
+	path := *file
+	if path == "" {
+		cfg, errLoad := conf.Load(*filename, nil)
+		if errLoad != nil {
+			return errLoad
+		}
+
+		if cfg.Audit.File == "" {
+			return fmt.Errorf("%s: the audit log is disabled (audit.file)", *filename)
+		}
+
+		path = cfg.Audit.File
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	records, err := audit.Query(f, filter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	encoder := json.NewEncoder(stdout)
+	for _, rec := range records {
+		err = encoder.Encode(rec)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseTime parses a time (RFC 3339, or a date), returns the zero time if the value is empty.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+// parsePullRequestRef parses a reference to a pull request: owner/repo#123.
+func parsePullRequestRef(ref string) (string, int, error) {
+	index := strings.LastIndex(ref, "#")
+	if index < 0 || !strings.Contains(ref[:index], "/") {
+		return "", 0, fmt.Errorf("invalid pull request %q: must be owner/repo#123", ref)
+	}
+
+	number, err := strconv.Atoi(ref[index+1:])
+	if err != nil || number <= 0 {
+		return "", 0, fmt.Errorf("invalid pull request %q: must be owner/repo#123", ref)
+	}
+
+	return ref[:index], number, nil
+}
+
+// runCommand runs a subcommand, returns false if the arguments are not a subcommand.
+func runCommand(ctx context.Context, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	var err error
+
+	switch args[0] {
+	case cmdValidate:
+		err = runValidate(args[1:], os.Stdout)
+	case cmdSchema:
+		err = runSchema(args[1:], os.Stdout)
+	case cmdAudit:
+		err = runAudit(args[1:], os.Stdout)
+	case cmdExplain:
+		setupLogger(false, "error")
+		err = runExplain(ctx, args[1:], os.Stdout)
+	default:
+		return false
+	}
+
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+		}
+
+		os.Exit(1)
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func Test_parsePullRequestRef(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		ref              string
+		expectedFullName string
+		expectedNumber   int
+		expectedErr      bool
+	}{
+		{
+			desc:             "repository",
+			ref:              "foo/bar#123",
+			expectedFullName: "foo/bar",
+			expectedNumber:   123,
+		},
+		{
+			desc:             "subgroup",
+			ref:              "group/subgroup/bar#1",
+			expectedFullName: "group/subgroup/bar",
+			expectedNumber:   1,
+		},
+		{
+			desc:        "missing number",
+			ref:         "foo/bar",
+			expectedErr: true,
+		},
+		{
+			desc:        "missing owner",
+			ref:         "bar#123",
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid number",
+			ref:         "foo/bar#abc",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			fullName, number, err := parsePullRequestRef(test.ref)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedFullName, fullName)
+			assert.Equal(t, test.expectedNumber, number)
+		})
+	}
+}
+
+func Test_runValidate(t *testing.T) {
+	stdout := &bytes.Buffer{}
+
+	err := runValidate([]string{"-config", "../pkg/conf/fixtures/config.yml"}, stdout)
+	require.NoError(t, err)
+
+	assert.Equal(t, "../pkg/conf/fixtures/config.yml: the configuration is valid.\n", stdout.String())
+
+	err = runValidate([]string{"-config", "../pkg/conf/fixtures/config_invalid.yml"}, stdout)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid configuration:")
+}
+
+func Test_runSchema(t *testing.T) {
+	stdout := &bytes.Buffer{}
+
+	err := runSchema([]string{"-repo"}, stdout)
+	require.NoError(t, err)
+
+	expected, err := ioutil.ReadFile("../pkg/conf/fixtures/repo_schema.json")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), stdout.String())
+}
+
+func Test_runAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	auditLog := audit.New(conf.Audit{File: path})
+
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, auditLog.Write(&audit.Record{Time: start, Repo: "foo/bar", PR: 1, HeadSHA: "aaa", Decision: audit.DecisionWait}))
+	require.NoError(t, auditLog.Write(&audit.Record{Time: start.Add(48 * time.Hour), Repo: "foo/bar", PR: 1, HeadSHA: "aaa", Decision: audit.DecisionMerge, MergeSHA: "bbb"}))
+	require.NoError(t, auditLog.Write(&audit.Record{Time: start.Add(48 * time.Hour), Repo: "foo/baz", PR: 1, HeadSHA: "ccc", Decision: audit.DecisionWait}))
+
+	stdout := &bytes.Buffer{}
+
+	err := runAudit([]string{"-file", path, "-repo", "foo/bar", "-pr", "1", "-since", "2021-03-02"}, stdout)
+	require.NoError(t, err)
+
+	expected := `{"time":"2021-03-03T10:00:00Z","repo":"foo/bar","pr":1,"headSha":"aaa","decision":"merge","mergeSha":"bbb"}
+`
+
+	assert.Equal(t, expected, stdout.String())
+
+	err = runAudit([]string{"-file", path, "-until", "yesterday"}, stdout)
+	require.Error(t, err)
+
+	err = runAudit([]string{"-config", "../pkg/conf/fixtures/config.yml"}, stdout)
+	require.EqualError(t, err, "../pkg/conf/fixtures/config.yml: the audit log is disabled (audit.file)")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// daemon runs the sweep periodically until the context is canceled.
+// A tick is skipped if the previous sweep is still running.
+func daemon(ctx context.Context, cfg conf.Daemon, sweep func(ctx context.Context) error) {
+	log.Info().Msgf("Daemon started: interval %s, jitter %s.", cfg.Interval, cfg.Jitter)
+
+	var running int32
+	var wg sync.WaitGroup
+
+	// the first sweep starts immediately.
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Daemon stopping: waiting for the in-flight sweep.")
+			wg.Wait()
+			return
+
+		case <-timer.C:
+			if atomic.CompareAndSwapInt32(&running, 0, 1) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer atomic.StoreInt32(&running, 0)
+
+					err := sweep(ctx)
+					if err != nil {
+						log.Error().Err(err).Msg("Sweep error")
+					}
+				}()
+			} else {
+				log.Warn().Msg("The previous sweep is still running: tick skipped.")
+			}
+
+			timer.Reset(nextDelay(cfg))
+		}
+	}
+}
+
+// nextDelay computes the delay before the next tick: the interval plus a random jitter.
+func nextDelay(cfg conf.Daemon) time.Duration {
+	if cfg.Jitter <= 0 {
+		return cfg.Interval
+	}
+
+	//nolint:gosec // the jitter doesn't need a cryptographically secure random.
+	return cfg.Interval + time.Duration(rand.Int63n(int64(cfg.Jitter)))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func Test_nextDelay(t *testing.T) {
+	cfg := conf.Daemon{Interval: time.Minute, Jitter: 10 * time.Second}
+
+	for i := 0; i < 100; i++ {
+		delay := nextDelay(cfg)
+
+		assert.GreaterOrEqual(t, int64(delay), int64(time.Minute))
+		assert.Less(t, int64(delay), int64(time.Minute+10*time.Second))
+	}
+
+	assert.Equal(t, time.Minute, nextDelay(conf.Daemon{Interval: time.Minute}))
+}
+
+func Test_daemon(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls, inFlight, overlaps int32
+
+	sweep := func(ctx context.Context) error {
+		if atomic.AddInt32(&inFlight, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		defer atomic.AddInt32(&inFlight, -1)
+
+		atomic.AddInt32(&calls, 1)
+
+		// slower than the interval: some ticks must be skipped.
+		time.Sleep(30 * time.Millisecond)
+
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		daemon(ctx, conf.Daemon{Interval: 5 * time.Millisecond}, sweep)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the daemon doesn't stop")
+	}
+
+	assert.Zero(t, atomic.LoadInt32(&inFlight))
+	assert.Zero(t, atomic.LoadInt32(&overlaps))
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(1))
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// dashboard the state of the repositories found by the last sweeps (read-only).
+// The dashboard is shared by the bots of the successive configurations.
+type dashboard struct {
+	mu    sync.RWMutex
+	repos map[string]*repoStatus
+}
+
+// repoStatus the state of a repository.
+type repoStatus struct {
+	Repo string `json:"repo"`
+	// UpdatedAt the time of the last processing of the repository.
+	UpdatedAt time.Time `json:"updatedAt"`
+	// MergeQueue true if the pull requests are merged by the merge queue.
+	MergeQueue bool `json:"mergeQueue,omitempty"`
+	// PullRequests the pull requests found by the search, in line: the current pull request first.
+	PullRequests []pullStatus `json:"pullRequests"`
+	// Current the number of the current pull request, 0 if none.
+	Current int `json:"current,omitempty"`
+	// Reason the reason of the choice of the current pull request (search.Reason*), or the reason of the absence of choice.
+	Reason string `json:"reason,omitempty"`
+	// LastOutcome the result of the last processing of a pull request.
+	LastOutcome *outcome `json:"lastOutcome,omitempty"`
+}
+
+// pullStatus the state of a pull request.
+type pullStatus struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Position the position in line, starting at 1.
+	Position int `json:"position"`
+	// Retry the retry counter (markers.mergeRetryPrefix), 0 if none.
+	Retry int `json:"retry,omitempty"`
+}
+
+// outcome the result of the processing of a pull request.
+type outcome struct {
+	Time   time.Time `json:"time"`
+	Number int       `json:"number"`
+	Error  string    `json:"error,omitempty"`
+}
+
+func newDashboard() *dashboard {
+	return &dashboard{repos: make(map[string]*repoStatus)}
+}
+
+// retain removes the repositories of an owner which are not in the results of a sweep.
+func (d *dashboard) retain(ownerName string, results map[string][]*github.Issue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for fullName := range d.repos {
+		owner, _ := splitFullName(fullName)
+		if !strings.EqualFold(owner, ownerName) {
+			continue
+		}
+
+		if _, ok := results[fullName]; !ok {
+			delete(d.repos, fullName)
+		}
+	}
+}
+
+// remove removes a repository.
+func (d *dashboard) remove(fullName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.repos, fullName)
+}
+
+// update updates the pull requests of a repository, and the choice of the current pull request.
+func (d *dashboard) update(fullName string, issues []*github.Issue, markers conf.Markers, mergeQueue bool, current *github.Issue, reason string) {
+	status := &repoStatus{
+		Repo:       fullName,
+		UpdatedAt:  time.Now(),
+		MergeQueue: mergeQueue,
+		Current:    current.GetNumber(),
+		Reason:     reason,
+	}
+
+	// the current pull request first, then the order of the finder (the priority labels, then the oldest).
+	if current != nil {
+		status.PullRequests = append(status.PullRequests, newPullStatus(current, markers))
+	}
+
+	for _, issue := range issues {
+		if issue.GetNumber() != current.GetNumber() {
+			status.PullRequests = append(status.PullRequests, newPullStatus(issue, markers))
+		}
+	}
+
+	for i := range status.PullRequests {
+		status.PullRequests[i].Position = i + 1
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if previous, ok := d.repos[fullName]; ok {
+		status.LastOutcome = previous.LastOutcome
+	}
+
+	d.repos[fullName] = status
+}
+
+// setOutcome sets the result of the processing of a pull request.
+// The token is hidden in the error.
+func (d *dashboard) setOutcome(fullName string, number int, err error, token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status, ok := d.repos[fullName]
+	if !ok {
+		return
+	}
+
+	status.LastOutcome = &outcome{Time: time.Now(), Number: number}
+	if err != nil {
+		status.LastOutcome.Error = err.Error()
+		if token != "" {
+			status.LastOutcome.Error = strings.ReplaceAll(status.LastOutcome.Error, token, "xxx")
+		}
+	}
+}
+
+// snapshot gets a copy of the state of the repositories, sorted by name.
+func (d *dashboard) snapshot() []repoStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	repos := make([]repoStatus, 0, len(d.repos))
+	for _, status := range d.repos {
+		repos = append(repos, *status)
+	}
+
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repo < repos[j].Repo })
+
+	return repos
+}
+
+func newPullStatus(issue *github.Issue, markers conf.Markers) pullStatus {
+	return pullStatus{
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		URL:       issue.GetHTMLURL(),
+		Author:    issue.GetUser().GetLogin(),
+		UpdatedAt: issue.GetUpdatedAt(),
+		Retry:     retryNumber(issue.Labels, markers.MergeRetryPrefix),
+	}
+}
+
+// retryNumber gets the retry counter from the labels, 0 if none.
+func retryNumber(labels []*github.Label, prefix string) int {
+	if prefix == "" {
+		return 0
+	}
+
+	for _, lbl := range labels {
+		if strings.HasPrefix(lbl.GetName(), prefix) {
+			number, err := strconv.Atoi(strings.TrimPrefix(lbl.GetName(), prefix))
+			if err == nil {
+				return number
+			}
+		}
+	}
+
+	return 0
+}
+
+// handleDashboard displays the dashboard: HTML, or JSON with /dashboard.json.
+// The repositories can be filtered with the query parameter repo.
+func (s *server) handleDashboard(rw http.ResponseWriter, req *http.Request) {
+	b := s.bots.get()
+
+	if !b.cfg.Server.Dashboard.Enabled {
+		http.NotFound(rw, req)
+		return
+	}
+
+	if !isDashboardAuthorized(req, b.cfg.Server.Dashboard.Token) {
+		rw.Header().Set("WWW-Authenticate", `Basic realm="lobicornis"`)
+		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		log.Error().Str("method", req.Method).Msg("Invalid http method")
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	repos := b.board.snapshot()
+
+	if name := req.URL.Query().Get("repo"); name != "" {
+		var filtered []repoStatus
+		for _, status := range repos {
+			if strings.EqualFold(status.Repo, name) {
+				filtered = append(filtered, status)
+			}
+		}
+
+		repos = filtered
+	}
+
+	if repos == nil {
+		repos = []repoStatus{}
+	}
+
+	if strings.HasSuffix(req.URL.Path, ".json") {
+		rw.Header().Set("Content-Type", "application/json")
+
+		err := json.NewEncoder(rw).Encode(repos)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to write the dashboard")
+		}
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	err := dashboardTemplate.Execute(rw, repos)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to write the dashboard")
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Myrmica Lobicornis</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.current { font-weight: bold; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Myrmica Lobicornis</h1>
+{{- range . }}
+<h2>{{ .Repo }}</h2>
+<p>
+Updated at {{ formatTime .UpdatedAt }}.
+{{- if .MergeQueue }} Merge queue.{{ end }}
+{{- if .Current }} Current pull request: #{{ .Current }} ({{ .Reason }}).{{ else if .Reason }} No current pull request ({{ .Reason }}).{{ end }}
+</p>
+<table>
+<tr><th>Position</th><th>Pull request</th><th>Author</th><th>Updated at</th><th>Retry</th></tr>
+{{- $current := .Current }}
+{{- range .PullRequests }}
+<tr{{ if eq .Number $current }} class="current"{{ end }}>
+<td>{{ .Position }}</td>
+<td>{{ if .URL }}<a href="{{ .URL }}">#{{ .Number }}</a>{{ else }}#{{ .Number }}{{ end }} {{ .Title }}</td>
+<td>{{ .Author }}</td>
+<td>{{ formatTime .UpdatedAt }}</td>
+<td>{{ .Retry }}</td>
+</tr>
+{{- end }}
+</table>
+{{- with .LastOutcome }}
+<p>Last outcome: {{ if .Number }}#{{ .Number }} {{ end }}at {{ formatTime .Time }}: {{ if .Error }}<span class="error">{{ .Error }}</span>{{ else }}processed{{ end }}.</p>
+{{- end }}
+{{- else }}
+<p>No pull request to merge.</p>
+{{- end }}
+</body>
+</html>
+`))
+
+// isDashboardAuthorized checks the token of a request: a bearer token, or the password of a basic authentication.
+// The dashboard is public if the token is not defined.
+func isDashboardAuthorized(req *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+
+	var candidate string
+	if _, password, ok := req.BasicAuth(); ok {
+		candidate = password
+	} else if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		candidate = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1
+}
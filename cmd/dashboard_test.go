@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/search"
+)
+
+func Test_dashboard(t *testing.T) {
+	markers := conf.Markers{MergeRetryPrefix: "bot/merge-retry-"}
+
+	issues := []*github.Issue{
+		{Number: github.Int(1), Title: github.String("first")},
+		{Number: github.Int(2), Title: github.String("second"), Labels: []*github.Label{{Name: github.String("bot/merge-retry-2")}}},
+		{Number: github.Int(3), Title: github.String("third")},
+	}
+
+	board := newDashboard()
+
+	board.update("foo/bar", issues, markers, false, issues[1], search.ReasonRetry)
+	board.setOutcome("foo/bar", 2, errors.New("checks status: failed with token secret"), "secret")
+
+	board.update("foo/baz", issues[:1], markers, false, issues[0], search.ReasonOldest)
+	board.update("qux/foo", issues[:1], markers, false, issues[0], search.ReasonOldest)
+
+	// the repository foo/baz is not found by the last sweep.
+	board.retain("foo", map[string][]*github.Issue{"foo/bar": issues})
+
+	cfg := conf.Configuration{Server: conf.Server{Dashboard: conf.Dashboard{Enabled: true}}}
+
+	srv := &server{bots: newActiveBot(&bot{cfg: cfg, board: board})}
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard.json", nil)
+	rw := httptest.NewRecorder()
+
+	srv.handleDashboard(rw, req)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var repos []repoStatus
+	err := json.NewDecoder(rw.Body).Decode(&repos)
+	require.NoError(t, err)
+
+	require.Len(t, repos, 2)
+
+	repo := repos[0]
+	assert.Equal(t, "foo/bar", repo.Repo)
+	assert.Equal(t, 2, repo.Current)
+	assert.Equal(t, search.ReasonRetry, repo.Reason)
+
+	var numbers, positions, retries []int
+	for _, pr := range repo.PullRequests {
+		numbers = append(numbers, pr.Number)
+		positions = append(positions, pr.Position)
+		retries = append(retries, pr.Retry)
+	}
+
+	assert.Equal(t, []int{2, 1, 3}, numbers)
+	assert.Equal(t, []int{1, 2, 3}, positions)
+	assert.Equal(t, []int{2, 0, 0}, retries)
+
+	require.NotNil(t, repo.LastOutcome)
+	assert.Equal(t, 2, repo.LastOutcome.Number)
+	assert.Equal(t, "checks status: failed with token xxx", repo.LastOutcome.Error)
+
+	assert.Equal(t, "qux/foo", repos[1].Repo)
+
+	// HTML
+	req = httptest.NewRequest(http.MethodGet, "/dashboard?repo=foo/bar", nil)
+	rw = httptest.NewRecorder()
+
+	srv.handleDashboard(rw, req)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), "<h2>foo/bar</h2>")
+	assert.Contains(t, rw.Body.String(), "Current pull request: #2 (retry).")
+	assert.NotContains(t, rw.Body.String(), "qux/foo")
+}
+
+func Test_dashboard_auth(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		config   conf.Dashboard
+		auth     func(req *http.Request)
+		expected int
+	}{
+		{
+			desc:     "disabled",
+			expected: http.StatusNotFound,
+		},
+		{
+			desc:     "public",
+			config:   conf.Dashboard{Enabled: true},
+			expected: http.StatusOK,
+		},
+		{
+			desc:     "missing token",
+			config:   conf.Dashboard{Enabled: true, Token: "secret"},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			desc:   "bearer token",
+			config: conf.Dashboard{Enabled: true, Token: "secret"},
+			auth: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer secret")
+			},
+			expected: http.StatusOK,
+		},
+		{
+			desc:   "basic authentication",
+			config: conf.Dashboard{Enabled: true, Token: "secret"},
+			auth: func(req *http.Request) {
+				req.SetBasicAuth("foo", "secret")
+			},
+			expected: http.StatusOK,
+		},
+		{
+			desc:   "invalid token",
+			config: conf.Dashboard{Enabled: true, Token: "secret"},
+			auth: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer foo")
+			},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			desc:   "token without scheme",
+			config: conf.Dashboard{Enabled: true, Token: "secret"},
+			auth: func(req *http.Request) {
+				req.Header.Set("Authorization", "secret")
+			},
+			expected: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := conf.Configuration{Server: conf.Server{Dashboard: test.config}}
+
+			srv := &server{bots: newActiveBot(&bot{cfg: cfg, board: newDashboard()})}
+
+			req := httptest.NewRequest(http.MethodGet, "/dashboard.json", nil)
+			if test.auth != nil {
+				test.auth(req)
+			}
+
+			rw := httptest.NewRecorder()
+
+			srv.handleDashboard(rw, req)
+
+			assert.Equal(t, test.expected, rw.Code)
+		})
+	}
+}
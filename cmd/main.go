@@ -4,27 +4,42 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
+	"os/signal"
 	"strings"
+	"syscall"
+	_ "time/tzdata" // the time zones of the merge windows, even without the time zone database of the system.
 
 	"github.com/google/go-github/v32/github"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/traefik/lobicornis/v2/pkg/conf"
-	"github.com/traefik/lobicornis/v2/pkg/repository"
-	"github.com/traefik/lobicornis/v2/pkg/search"
+	"github.com/traefik/lobicornis/v2/pkg/ghapp"
+	"github.com/traefik/lobicornis/v2/pkg/ratelimit"
+	"github.com/traefik/lobicornis/v2/pkg/tracing"
 	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
+	if runCommand(context.Background(), os.Args[1:]) {
+		return
+	}
+
 	filename := flag.String("config", "./lobicornis.yml", "Path to the configuration file.")
 	serverMode := flag.Bool("server", false, "Run as a web server.")
+	daemonMode := flag.Bool("daemon", false, "Run as a daemon: process the repositories periodically.")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration (the secrets are redacted).")
 	version := flag.Bool("version", false, "Display version information.")
 	help := flag.Bool("h", false, "Show this help.")
 
+	for _, path := range conf.Fields() {
+		flag.String(path, "", fmt.Sprintf("Overrides %s (env: %s).", path, conf.EnvName(path)))
+	}
+
 	flag.Usage = usage
 	flag.Parse()
 	if *help {
@@ -48,116 +63,124 @@ func main() {
 		return
 	}
 
-	cfg, err := conf.Load(*filename)
+	flags := getOverrides()
+
+	cfg, err := conf.Load(*filename, flags)
 	if err != nil {
 		log.Fatal().Err(err).Msg("unable to load config")
 	}
 
+	if *printConfig {
+		err = displayConfig(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("unable to print config")
+		}
+
+		return
+	}
+
 	setupLogger(cfg.Extra.DryRun, cfg.Extra.LogLevel)
 
-	if *serverMode {
-		err = launch(cfg)
+	for _, message := range cfg.Deprecations() {
+		log.Warn().Msg(message)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to setup the traces")
+	}
+
+	defer func() {
+		err := shutdownTracing(context.Background())
+		if err != nil {
+			log.Error().Err(err).Msg("unable to export the traces")
+		}
+	}()
+
+	// the secondary rate limits must be respected by all the concurrent workers, even during a reload.
+	transport := ratelimit.NewTransport(http.DefaultTransport, ratelimit.DefaultInterval)
+
+	b, err := newBot(ctx, cfg, transport)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to create the bot")
+	}
+
+	bots := newActiveBot(b)
+
+	if *serverMode || *daemonMode {
+		go bots.watch(ctx, *filename, flags)
+	}
+
+	switch {
+	case *serverMode:
+		err = launch(ctx, bots, *daemonMode)
 		if err != nil {
 			log.Fatal().Err(err).Msg("unable to launch the server")
 		}
-	} else {
-		err = run(cfg)
+	case *daemonMode:
+		daemon(ctx, cfg.Daemon, bots.run)
+	default:
+		err = b.run(ctx)
 		if err != nil {
 			log.Fatal().Err(err).Msg("unable to run the command")
 		}
 	}
 }
 
-func launch(cfg conf.Configuration) error {
-	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-		if req.Method != http.MethodGet {
-			log.Error().Str("method", req.Method).Msg("Invalid http method")
-			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-			return
-		}
+// getOverrides gets the configuration fields defined by the flags.
+func getOverrides() conf.Overrides {
+	fields := conf.Fields()
 
-		err := run(cfg)
-		if err != nil {
-			log.Error().Err(err).Msg("Report error")
-			http.Error(rw, "Report error.", http.StatusInternalServerError)
-			return
-		}
-
-		_, err = fmt.Fprint(rw, "Myrmica Lobicornis: Scheduled.\n")
-		if err != nil {
-			log.Error().Err(err).Msg("Report error")
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
-			return
+	overrides := conf.Overrides{}
+	flag.Visit(func(f *flag.Flag) {
+		for _, path := range fields {
+			if f.Name == path {
+				overrides[path] = f.Value.String()
+			}
 		}
 	})
 
-	return http.ListenAndServe(":"+strconv.Itoa(cfg.Server.Port), handler)
+	return overrides
 }
 
-func run(cfg conf.Configuration) error {
-	ctx := context.Background()
-
-	client := newGitHubClient(ctx, cfg.Github.Token, cfg.Github.URL)
+// displayConfig prints the configuration (YAML) without the secrets.
+func displayConfig(cfg conf.Configuration) error {
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
 
-	finder := search.New(client, cfg.Markers, cfg.Retry)
-
-	// search PRs with the FF merge method.
-	ffResults, err := finder.Search(ctx, cfg.Github.User,
-		search.WithLabels(cfg.Markers.MergeMethodPrefix+conf.MergeMethodFastForward),
-		search.WithExcludedLabels(cfg.Markers.NoMerge, cfg.Markers.NeedMerge))
+	err := encoder.Encode(cfg.Redact())
 	if err != nil {
 		return err
 	}
 
-	// search NeedMerge
-	results, err := finder.Search(ctx, cfg.Github.User,
-		search.WithLabels(cfg.Markers.NeedMerge),
-		search.WithExcludedLabels(cfg.Markers.NeedHumanMerge, cfg.Markers.NoMerge))
+	return encoder.Close()
+}
+
+// newGitHubApp creates the GitHub App used to create the installation tokens of the owners.
+func newGitHubApp(cfg conf.Github) (*ghapp.App, error) {
+	privateKey, err := ioutil.ReadFile(cfg.App.PrivateKey)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("unable to read the private key of the GitHub App: %w", err)
 	}
 
-	for fullName, issues := range results {
-		logger := log.With().Str("repo", fullName).Logger()
-
-		if _, ok := ffResults[fullName]; ok {
-			logger.Info().Msgf("Waiting for the merge of pull request with the label: %s", cfg.Markers.MergeMethodPrefix+conf.MergeMethodFastForward)
-			continue
-		}
-
-		repoConfig := getRepoConfig(cfg, fullName)
-
-		issue, err := finder.GetCurrentPull(logger.WithContext(ctx), issues)
-		if err != nil {
-			logger.Error().Err(err).Msg("unable to get the current pull request")
-			continue
-		}
-
-		if issue == nil {
-			logger.Debug().Msg("Nothing to merge.")
-			continue
-		}
-
-		repo := repository.New(client, fullName, cfg.Github.Token, cfg.Markers, cfg.Retry, cfg.Git, repoConfig, cfg.Extra)
-
-		loggerIssue := logger.With().Int("pr", issue.GetNumber()).Logger()
-
-		err = repo.Process(loggerIssue.WithContext(ctx), issue.GetNumber())
-		if err != nil {
-			loggerIssue.Error().Err(err).Msg("Failed to process")
-		}
+	app, err := ghapp.New(cfg.App.ID, privateKey, cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the GitHub App: %w", err)
 	}
 
-	return nil
+	return app, nil
 }
 
 // newGitHubClient create a new GitHub client.
-func newGitHubClient(ctx context.Context, token string, gitHubURL string) *github.Client {
-	var tc *http.Client
+// Each API call creates a span.
+func newGitHubClient(ctx context.Context, transport http.RoundTripper, ts oauth2.TokenSource, gitHubURL string) *github.Client {
+	tc := &http.Client{Transport: tracing.Transport(transport)}
 
-	if len(token) != 0 {
-		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-		tc = oauth2.NewClient(ctx, ts)
+	if ts != nil {
+		tc = oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, tc), ts)
 	}
 
 	client := github.NewClient(tc)
@@ -172,17 +195,25 @@ func newGitHubClient(ctx context.Context, token string, gitHubURL string) *githu
 	return client
 }
 
-func getRepoConfig(cfg conf.Configuration, repoName string) conf.RepoConfig {
-	if repoCfg, ok := cfg.Repositories[repoName]; ok && repoCfg != nil {
-		return *repoCfg
-	}
-
-	return cfg.Default
+// newHTTPClient creates the HTTP client of the forges (GitLab, Gitea): each API call creates a span.
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
 }
 
 func usage() {
 	_, _ = os.Stderr.WriteString("Myrmica Lobicornis:\n")
 	flag.PrintDefaults()
+	_, _ = os.Stderr.WriteString(`
+Commands:
+  validate [-config file]
+        Validate the configuration file, without any call to the forge.
+  explain [-config file] owner/repo#123
+        Explain the decision of the bot for a pull request, without any change.
+  schema [-repo]
+        Print the JSON Schema of the configuration file (-repo: of the repository configuration file).
+  audit [-config file] [-file audit.jsonl] [-repo owner/repo] [-pr 123] [-since time] [-until time]
+        Print the records of the audit log matching the filters (JSON Lines).
+`)
 }
 
 // setupLogger is configuring the logger.
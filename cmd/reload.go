@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// reloadInterval the interval between 2 checks of the modification of the configuration file.
+const reloadInterval = 10 * time.Second
+
+// activeBot the bot of the active configuration.
+// The bot is swapped when the configuration is reloaded: the sweeps in progress keep the previous bot.
+type activeBot struct {
+	value atomic.Value
+}
+
+func newActiveBot(b *bot) *activeBot {
+	a := &activeBot{}
+	a.value.Store(b)
+
+	return a
+}
+
+func (a *activeBot) get() *bot {
+	return a.value.Load().(*bot)
+}
+
+// run runs a sweep with the active bot.
+func (a *activeBot) run(ctx context.Context) error {
+	return a.get().run(ctx)
+}
+
+// watch reloads the configuration when the file is modified, or when the process receives SIGHUP.
+func (a *activeBot) watch(ctx context.Context, filename string, flags conf.Overrides) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	lastMod := modTime(filename)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hup:
+			log.Info().Msg("SIGHUP received: reloading the configuration.")
+
+			lastMod = modTime(filename)
+			a.reload(ctx, filename, flags)
+
+		case <-ticker.C:
+			mod := modTime(filename)
+			if mod.Equal(lastMod) {
+				continue
+			}
+
+			lastMod = mod
+			a.reload(ctx, filename, flags)
+		}
+	}
+}
+
+// reload loads the configuration and swaps the active bot.
+// An invalid configuration is rejected: the previous configuration stays active.
+// The environment variables and the flags are applied again.
+func (a *activeBot) reload(ctx context.Context, filename string, flags conf.Overrides) {
+	current := a.get()
+
+	cfg, err := conf.Load(filename, flags)
+	if err != nil {
+		var diff string
+		if invalid, errRead := conf.Read(filename, flags); errRead == nil {
+			diff = strings.Join(conf.Diff(current.cfg, invalid), "\n")
+		}
+
+		log.Error().Err(err).Msgf("Invalid configuration: the new configuration is rejected.\n%s", diff)
+		return
+	}
+
+	diff := strings.Join(conf.Diff(current.cfg, cfg), "\n")
+	if diff == "" {
+		log.Debug().Msg("The configuration is unchanged.")
+		return
+	}
+
+	b, err := current.reload(ctx, cfg)
+	if err != nil {
+		log.Error().Err(err).Msgf("Unable to apply the configuration: the new configuration is rejected.\n%s", diff)
+		return
+	}
+
+	setupLogger(cfg.Extra.DryRun, cfg.Extra.LogLevel)
+
+	for _, message := range cfg.Deprecations() {
+		log.Warn().Msg(message)
+	}
+
+	a.value.Store(b)
+
+	log.Info().Msgf("Configuration reloaded.\n%s", diff)
+}
+
+// reload creates a bot with a new configuration.
+// The transport, the locks, the cache, and the dashboard are shared with the previous bot, and the audit log if its configuration is unchanged.
+func (b *bot) reload(ctx context.Context, cfg conf.Configuration) (*bot, error) {
+	nb, err := newBot(ctx, cfg, b.transport)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the bot: %w", err)
+	}
+
+	nb.locks = b.locks
+	nb.cache = b.cache
+	nb.board = b.board
+
+	if cfg.Audit == b.cfg.Audit {
+		nb.auditLog = b.auditLog
+	}
+
+	if cfg.Git.Cache != b.cfg.Git.Cache {
+		log.Warn().Msg("The changes of git.cache require a restart.")
+	}
+
+	if cfg.Server.Port != b.cfg.Server.Port || cfg.Daemon != b.cfg.Daemon || cfg.Tracing != b.cfg.Tracing {
+		log.Warn().Msg("The changes of server.port, daemon, and tracing require a restart.")
+	}
+
+	return nb, nil
+}
+
+func modTime(filename string) time.Time {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/ratelimit"
+)
+
+const reloadConfig = `
+github:
+  user: foo
+  token: XXXX
+git:
+  email: bot@example.com
+  userName: botname
+default:
+  minReview: %s
+`
+
+func Test_activeBot_reload(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "lobicornis.yml")
+
+	writeConfig := func(minReview string) {
+		err := ioutil.WriteFile(filename, []byte(fmt.Sprintf(reloadConfig, minReview)), 0o600)
+		require.NoError(t, err)
+	}
+
+	writeConfig("1")
+
+	cfg, err := conf.Load(filename, nil)
+	require.NoError(t, err)
+
+	b, err := newBot(context.Background(), cfg, ratelimit.NewTransport(http.DefaultTransport, ratelimit.DefaultInterval))
+	require.NoError(t, err)
+
+	bots := newActiveBot(b)
+
+	// valid configuration.
+	writeConfig("2")
+	bots.reload(context.Background(), filename, nil)
+
+	reloaded := bots.get()
+	require.NotSame(t, b, reloaded)
+	assert.Equal(t, 2, reloaded.cfg.Default.GetMinReview())
+	assert.Same(t, b.locks, reloaded.locks)
+	assert.Same(t, b.board, reloaded.board)
+	assert.Same(t, b.transport, reloaded.transport)
+
+	// invalid configuration: the previous configuration stays active.
+	writeConfig("-1")
+	bots.reload(context.Background(), filename, nil)
+
+	assert.Same(t, reloaded, bots.get())
+
+	// unchanged configuration.
+	writeConfig("2")
+	bots.reload(context.Background(), filename, nil)
+
+	assert.Same(t, reloaded, bots.get())
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+// server the web server.
+type server struct {
+	ctx      context.Context
+	bots     *activeBot
+	webhooks *webhookQueue
+}
+
+func launch(ctx context.Context, bots *activeBot, daemonMode bool) error {
+	cfg := bots.get().cfg
+
+	srv := &server{
+		ctx:  ctx,
+		bots: bots,
+		// the repositories are processed by the current bot: the configuration can be reloaded while a run is queued.
+		webhooks: newWebhookQueue(webhookWorkers, func(ctx context.Context, target webhookTarget) error {
+			return bots.get().runRepository(ctx, target)
+		}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", srv.handleTrigger)
+	mux.HandleFunc("/webhook", srv.handleWebhook)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/dashboard", srv.handleDashboard)
+	mux.HandleFunc("/dashboard.json", srv.handleDashboard)
+
+	if cfg.Server.WebhookSecret == "" {
+		log.Warn().Msg("The webhook endpoint is disabled: server.webhookSecret is not defined.")
+	}
+
+	if cfg.Server.Dashboard.Enabled && cfg.Server.Dashboard.Token == "" {
+		log.Warn().Msg("The dashboard is not protected: server.dashboard.token is not defined.")
+	}
+
+	httpServer := &http.Server{
+		Addr:    ":" + strconv.Itoa(cfg.Server.Port),
+		Handler: mux,
+	}
+
+	var wg sync.WaitGroup
+
+	if daemonMode {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			daemon(ctx, cfg.Daemon, bots.run)
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to shutdown the server")
+		}
+	}()
+
+	err := httpServer.ListenAndServe()
+
+	wg.Wait()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *server) handleTrigger(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		log.Error().Str("method", req.Method).Msg("Invalid http method")
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := s.bots.run(s.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Report error")
+		http.Error(rw, "Report error.", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = fmt.Fprint(rw, "Myrmica Lobicornis: Scheduled.\n")
+	if err != nil {
+		log.Error().Err(err).Msg("Report error")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
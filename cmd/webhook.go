@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/repository"
+	"github.com/traefik/lobicornis/v2/pkg/search"
+)
+
+const (
+	signature256Header = "X-Hub-Signature-256"
+
+	// maxPayloadSize GitHub caps the webhook payloads to 25MB.
+	maxPayloadSize = 25 << 20
+
+	// webhookWorkers the maximal number of repositories processed at the same time for the webhook events.
+	webhookWorkers = 4
+)
+
+// webhookTarget the pull requests concerned by a webhook event.
+type webhookTarget struct {
+	fullName string
+	// prNumbers the pull requests concerned by the event, empty if the event concerns all the pull requests of the repository.
+	prNumbers []int
+}
+
+func (s *server) handleWebhook(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		log.Error().Str("method", req.Method).Msg("Invalid http method")
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	b := s.bots.get()
+
+	// the secret can be removed by a reload of the configuration.
+	if b.cfg.Server.WebhookSecret == "" {
+		http.NotFound(rw, req)
+		return
+	}
+
+	logger := log.With().Str("delivery", github.DeliveryID(req)).Str("event", github.WebHookType(req)).Logger()
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, req.Body, maxPayloadSize))
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to read the webhook payload")
+		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	err = github.ValidateSignature(req.Header.Get(signature256Header), body, []byte(b.cfg.Server.WebhookSecret))
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid webhook signature")
+		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(req), body)
+	if err != nil {
+		logger.Debug().Err(err).Msg("Unsupported webhook event")
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	target, ok := getWebhookTarget(event)
+	if !ok {
+		logger.Debug().Msg("Ignored webhook event")
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	logger.Debug().Str("repo", target.fullName).Ints("prs", target.prNumbers).Msg("Webhook event received")
+
+	if !s.webhooks.push(s.ctx, target) {
+		logger.Debug().Str("repo", target.fullName).Msg("A run of the repository is already queued: the event is merged into it.")
+	}
+
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// getWebhookTarget gets the pull requests concerned by a webhook event.
+func getWebhookTarget(event interface{}) (webhookTarget, bool) {
+	switch evt := event.(type) {
+	case *github.PullRequestEvent:
+		if evt.GetAction() == "closed" {
+			return webhookTarget{}, false
+		}
+
+		return webhookTarget{fullName: evt.GetRepo().GetFullName(), prNumbers: []int{evt.GetNumber()}}, true
+
+	case *github.PullRequestReviewEvent:
+		return webhookTarget{fullName: evt.GetRepo().GetFullName(), prNumbers: []int{evt.GetPullRequest().GetNumber()}}, true
+
+	case *github.CheckSuiteEvent:
+		if evt.GetAction() != "completed" {
+			return webhookTarget{}, false
+		}
+
+		target := webhookTarget{fullName: evt.GetRepo().GetFullName()}
+
+		if strings.HasPrefix(evt.GetCheckSuite().GetHeadBranch(), repository.QueueBranchPrefix) {
+			// check suites on the integration branch of a merge queue.
+			return target, true
+		}
+
+		for _, pr := range evt.GetCheckSuite().PullRequests {
+			target.prNumbers = append(target.prNumbers, pr.GetNumber())
+		}
+
+		if len(target.prNumbers) == 0 {
+			// check suites on a branch without pull request.
+			return webhookTarget{}, false
+		}
+
+		return target, true
+
+	case *github.StatusEvent:
+		if evt.GetState() == "pending" {
+			return webhookTarget{}, false
+		}
+
+		// the status is related to a commit, not to a pull request.
+		return webhookTarget{fullName: evt.GetRepo().GetFullName()}, true
+
+	case *github.LabelEvent:
+		return webhookTarget{fullName: evt.GetRepo().GetFullName()}, true
+
+	default:
+		return webhookTarget{}, false
+	}
+}
+
+// runRepository processes the current pull request of the repository targeted by a webhook event.
+func (b *bot) runRepository(ctx context.Context, target webhookTarget) error {
+	o, ok := b.getOwner(target.fullName)
+	if !ok {
+		log.Debug().Str("repo", target.fullName).Msg("The owner of the repository is not managed.")
+		return nil
+	}
+
+	err := b.locks.lock(ctx, target.fullName)
+	if err != nil {
+		return err
+	}
+
+	defer b.locks.unlock(target.fullName)
+
+	ffResults, results, err := o.searchPulls(ctx, search.WithRepository(target.fullName))
+	if err != nil {
+		return fmt.Errorf("unable to search pull requests: %w", err)
+	}
+
+	issues, ok := results[target.fullName]
+	if !ok {
+		log.Debug().Str("repo", target.fullName).Msg("Nothing to merge.")
+		b.board.remove(target.fullName)
+		return nil
+	}
+
+	b.processRepository(ctx, o, target.fullName, issues, ffResults, target.prNumbers)
+
+	return nil
+}
+
+// webhookQueue coalesces the webhook events by repository:
+// a repository has at most one run in progress and one queued run, the events received in the meantime are merged into the queued run.
+type webhookQueue struct {
+	run func(ctx context.Context, target webhookTarget) error
+
+	// slots limits the number of repositories processed at the same time.
+	slots chan struct{}
+
+	mu      sync.Mutex
+	queued  map[string]*webhookTarget
+	running map[string]bool
+}
+
+func newWebhookQueue(workers int, run func(ctx context.Context, target webhookTarget) error) *webhookQueue {
+	return &webhookQueue{
+		run:     run,
+		slots:   make(chan struct{}, workers),
+		queued:  make(map[string]*webhookTarget),
+		running: make(map[string]bool),
+	}
+}
+
+// push queues a run of the repository targeted by a webhook event.
+// Returns false if the event is merged into a run already queued.
+func (q *webhookQueue) push(ctx context.Context, target webhookTarget) bool {
+	key := strings.ToLower(target.fullName)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if queued, ok := q.queued[key]; ok {
+		queued.merge(target)
+		return false
+	}
+
+	q.queued[key] = &target
+
+	if q.running[key] {
+		// the worker of the repository takes the queued run at the end of the current run.
+		return true
+	}
+
+	q.running[key] = true
+
+	go q.work(ctx, key)
+
+	return true
+}
+
+// work runs the queued runs of a repository, until the queue of the repository is empty.
+func (q *webhookQueue) work(ctx context.Context, key string) {
+	for {
+		q.mu.Lock()
+		target, ok := q.queued[key]
+		if !ok {
+			delete(q.running, key)
+			q.mu.Unlock()
+			return
+		}
+		delete(q.queued, key)
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			delete(q.running, key)
+			q.mu.Unlock()
+			return
+		case q.slots <- struct{}{}:
+		}
+
+		err := q.run(ctx, *target)
+
+		<-q.slots
+
+		if err != nil {
+			log.Error().Err(err).Str("repo", target.fullName).Msg("Report error")
+		}
+	}
+}
+
+// merge merges the pull requests concerned by another event on the same repository.
+func (t *webhookTarget) merge(other webhookTarget) {
+	// an empty list concerns all the pull requests of the repository.
+	if len(t.prNumbers) == 0 || len(other.prNumbers) == 0 {
+		t.prNumbers = nil
+		return
+	}
+
+	for _, number := range other.prNumbers {
+		if !containsNumber(t.prNumbers, number) {
+			t.prNumbers = append(t.prNumbers, number)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func Test_getWebhookTarget(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		eventType string
+		payload   string
+		expected  webhookTarget
+		ignored   bool
+	}{
+		{
+			desc:      "pull request labeled",
+			eventType: "pull_request",
+			payload:   `{"action":"labeled","number":12,"repository":{"full_name":"foo/bar"}}`,
+			expected:  webhookTarget{fullName: "foo/bar", prNumbers: []int{12}},
+		},
+		{
+			desc:      "pull request closed",
+			eventType: "pull_request",
+			payload:   `{"action":"closed","number":12,"repository":{"full_name":"foo/bar"}}`,
+			ignored:   true,
+		},
+		{
+			desc:      "pull request review",
+			eventType: "pull_request_review",
+			payload:   `{"action":"submitted","pull_request":{"number":13},"repository":{"full_name":"foo/bar"}}`,
+			expected:  webhookTarget{fullName: "foo/bar", prNumbers: []int{13}},
+		},
+		{
+			desc:      "check suite completed",
+			eventType: "check_suite",
+			payload:   `{"action":"completed","check_suite":{"pull_requests":[{"number":1},{"number":2}]},"repository":{"full_name":"foo/bar"}}`,
+			expected:  webhookTarget{fullName: "foo/bar", prNumbers: []int{1, 2}},
+		},
+		{
+			desc:      "check suite requested",
+			eventType: "check_suite",
+			payload:   `{"action":"requested","check_suite":{"pull_requests":[{"number":1}]},"repository":{"full_name":"foo/bar"}}`,
+			ignored:   true,
+		},
+		{
+			desc:      "check suite without pull request",
+			eventType: "check_suite",
+			payload:   `{"action":"completed","check_suite":{"pull_requests":[]},"repository":{"full_name":"foo/bar"}}`,
+			ignored:   true,
+		},
+		{
+			desc:      "check suite on a merge queue",
+			eventType: "check_suite",
+			payload:   `{"action":"completed","check_suite":{"head_branch":"lobicornis-queue/master","pull_requests":[]},"repository":{"full_name":"foo/bar"}}`,
+			expected:  webhookTarget{fullName: "foo/bar"},
+		},
+		{
+			desc:      "status success",
+			eventType: "status",
+			payload:   `{"state":"success","sha":"aaa","repository":{"full_name":"foo/bar"}}`,
+			expected:  webhookTarget{fullName: "foo/bar"},
+		},
+		{
+			desc:      "status pending",
+			eventType: "status",
+			payload:   `{"state":"pending","sha":"aaa","repository":{"full_name":"foo/bar"}}`,
+			ignored:   true,
+		},
+		{
+			desc:      "label",
+			eventType: "label",
+			payload:   `{"action":"edited","label":{"name":"status/3-needs-merge"},"repository":{"full_name":"foo/bar"}}`,
+			expected:  webhookTarget{fullName: "foo/bar"},
+		},
+		{
+			desc:      "ping",
+			eventType: "ping",
+			payload:   `{"zen":"Keep it logically awesome."}`,
+			ignored:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			event, err := github.ParseWebHook(test.eventType, []byte(test.payload))
+			require.NoError(t, err)
+
+			target, ok := getWebhookTarget(event)
+
+			assert.Equal(t, !test.ignored, ok)
+			assert.Equal(t, test.expected, target)
+		})
+	}
+}
+
+func TestServer_handleWebhook(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		method      string
+		eventType   string
+		payload     string
+		secret      string
+		expected    int
+		noSignature bool
+	}{
+		{
+			desc:      "invalid method",
+			method:    http.MethodGet,
+			eventType: "ping",
+			payload:   `{}`,
+			secret:    "secret",
+			expected:  http.StatusMethodNotAllowed,
+		},
+		{
+			desc:      "invalid signature",
+			method:    http.MethodPost,
+			eventType: "ping",
+			payload:   `{}`,
+			secret:    "other",
+			expected:  http.StatusUnauthorized,
+		},
+		{
+			desc:        "missing signature",
+			method:      http.MethodPost,
+			eventType:   "ping",
+			payload:     `{}`,
+			secret:      "secret",
+			expected:    http.StatusUnauthorized,
+			noSignature: true,
+		},
+		{
+			desc:      "ignored event",
+			method:    http.MethodPost,
+			eventType: "ping",
+			payload:   `{"zen":"Keep it logically awesome."}`,
+			secret:    "secret",
+			expected:  http.StatusNoContent,
+		},
+		{
+			desc:      "unsupported event",
+			method:    http.MethodPost,
+			eventType: "foo",
+			payload:   `{}`,
+			secret:    "secret",
+			expected:  http.StatusNoContent,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			srv := &server{bots: newActiveBot(&bot{cfg: conf.Configuration{Server: conf.Server{WebhookSecret: "secret"}}})}
+
+			req := httptest.NewRequest(test.method, "/webhook", strings.NewReader(test.payload))
+			req.Header.Set("X-GitHub-Event", test.eventType)
+			req.Header.Set("Content-Type", "application/json")
+
+			if !test.noSignature {
+				req.Header.Set(signature256Header, sign(test.payload, test.secret))
+			}
+
+			rw := httptest.NewRecorder()
+
+			srv.handleWebhook(rw, req)
+
+			assert.Equal(t, test.expected, rw.Code)
+		})
+	}
+}
+
+func Test_webhookQueue(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var runs []webhookTarget
+
+	done := make(chan struct{})
+
+	queue := newWebhookQueue(webhookWorkers, func(_ context.Context, target webhookTarget) error {
+		started <- struct{}{}
+		<-release
+
+		mu.Lock()
+		runs = append(runs, target)
+		if len(runs) == 2 {
+			close(done)
+		}
+		mu.Unlock()
+
+		return nil
+	})
+
+	ctx := context.Background()
+
+	require.True(t, queue.push(ctx, webhookTarget{fullName: "foo/bar", prNumbers: []int{1}}))
+
+	// the first run is in progress.
+	<-started
+
+	var wg sync.WaitGroup
+	for i := 2; i <= 11; i++ {
+		wg.Add(1)
+		go func(number int) {
+			defer wg.Done()
+			queue.push(ctx, webhookTarget{fullName: "foo/bar", prNumbers: []int{number%3 + 2}})
+		}(i)
+	}
+
+	wg.Wait()
+
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	// the queue of the repository is empty.
+	require.Eventually(t, func() bool {
+		queue.mu.Lock()
+		defer queue.mu.Unlock()
+
+		return len(queue.running) == 0 && len(queue.queued) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, runs, 2)
+	assert.Equal(t, []int{1}, runs[0].prNumbers)
+	assert.ElementsMatch(t, []int{2, 3, 4}, runs[1].prNumbers)
+}
+
+func Test_webhookTarget_merge(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		target   []int
+		other    []int
+		expected []int
+	}{
+		{
+			desc:     "pull requests",
+			target:   []int{1, 2},
+			other:    []int{2, 3},
+			expected: []int{1, 2, 3},
+		},
+		{
+			desc:   "all the pull requests",
+			target: []int{1},
+		},
+		{
+			desc:  "already all the pull requests",
+			other: []int{1},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			target := webhookTarget{fullName: "foo/bar", prNumbers: test.target}
+			target.merge(webhookTarget{fullName: "foo/bar", prNumbers: test.other})
+
+			assert.Equal(t, test.expected, target.prNumbers)
+		})
+	}
+}
+
+func sign(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
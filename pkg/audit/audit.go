@@ -0,0 +1,207 @@
+// Package audit the append-only audit log of the decisions and the changes of the bot.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// The decisions of the bot for a pull request.
+const (
+	DecisionMerge    = "merge"
+	DecisionUpdate   = "update"
+	DecisionQueue    = "queue"
+	DecisionWait     = "wait"
+	DecisionEscalate = "escalate"
+	DecisionNone     = "none"
+)
+
+// Record the decision of the bot for a pull request, and the changes made by the bot.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Repo    string    `json:"repo"`
+	PR      int       `json:"pr"`
+	HeadSHA string    `json:"headSha"`
+	DryRun  bool      `json:"dryRun,omitempty"`
+
+	// Gates the evaluated conditions, in the order of the evaluation.
+	Gates []Gate `json:"gates,omitempty"`
+	// Reviews the reviews evaluated by the bot.
+	Reviews     []Review `json:"reviews,omitempty"`
+	MergeMethod string   `json:"mergeMethod,omitempty"`
+
+	LabelsAdded   []string `json:"labelsAdded,omitempty"`
+	LabelsRemoved []string `json:"labelsRemoved,omitempty"`
+	Comments      []string `json:"comments,omitempty"`
+
+	Decision string `json:"decision"`
+	// MergeSHA the SHA of the merge commit (the head of the pull request for the merge method ff,
+	// the head of the integration branch for the merge queue).
+	// Empty for Gitea: the merge API doesn't return the merge commit.
+	MergeSHA string `json:"mergeSha,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Gate the evaluation of a condition.
+type Gate struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Review the state of the review of a user.
+type Review struct {
+	User  string `json:"user"`
+	State string `json:"state"`
+}
+
+// AddGate records the evaluation of a condition.
+func (r *Record) AddGate(name string, passed bool, detail string) {
+	r.Gates = append(r.Gates, Gate{Name: name, Passed: passed, Detail: detail})
+}
+
+// AddLabels records the labels added on the pull request.
+func (r *Record) AddLabels(labels ...string) {
+	r.LabelsAdded = append(r.LabelsAdded, labels...)
+}
+
+// RemoveLabels records the labels removed from the pull request.
+func (r *Record) RemoveLabels(labels ...string) {
+	r.LabelsRemoved = append(r.LabelsRemoved, labels...)
+}
+
+// AddComment records a comment posted on the pull request.
+func (r *Record) AddComment(comment string) {
+	r.Comments = append(r.Comments, comment)
+}
+
+type recordKey struct{}
+
+// NewContext returns a context with a record.
+func NewContext(ctx context.Context, rec *Record) context.Context {
+	return context.WithValue(ctx, recordKey{}, rec)
+}
+
+// FromContext gets the record of a context.
+// Returns a discarded record if there is no record (ex: the explain command).
+func FromContext(ctx context.Context) *Record {
+	rec, ok := ctx.Value(recordKey{}).(*Record)
+	if !ok {
+		return &Record{}
+	}
+
+	return rec
+}
+
+// Log an append-only audit log (JSON Lines).
+type Log struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// New creates a new audit log.
+// Returns nil if the audit log is disabled.
+func New(cfg conf.Audit) *Log {
+	if cfg.File == "" {
+		return nil
+	}
+
+	return &Log{path: cfg.File}
+}
+
+// Write appends a record to the audit log.
+func (l *Log) Write(rec *Record) error {
+	if l == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("unable to marshal the record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = os.MkdirAll(filepath.Dir(l.path), 0o750)
+	if err != nil {
+		return fmt.Errorf("unable to create the directory of the audit log: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("unable to open the audit log: %w", err)
+	}
+
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to write the audit log: %w", err)
+	}
+
+	return file.Close()
+}
+
+// Filter the criteria of a query.
+// The zero values match all the records.
+type Filter struct {
+	// Repo the full name of a repository (owner/name).
+	Repo string
+	PR   int
+	// Since the records written at or after this time.
+	Since time.Time
+	// Until the records written before this time.
+	Until time.Time
+}
+
+// Match checks if a record matches the filter.
+func (f Filter) Match(rec Record) bool {
+	if f.Repo != "" && f.Repo != rec.Repo {
+		return false
+	}
+
+	if f.PR != 0 && f.PR != rec.PR {
+		return false
+	}
+
+	if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && !rec.Time.Before(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Query reads the records matching a filter, in the order of the audit log.
+func Query(r io.Reader, filter Filter) ([]Record, error) {
+	decoder := json.NewDecoder(r)
+
+	var records []Record
+	for i := 1; ; i++ {
+		var rec Record
+		err := decoder.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the record %d: %w", i, err)
+		}
+
+		if filter.Match(rec) {
+			records = append(records, rec)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func TestLog_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+
+	auditLog := New(conf.Audit{File: path})
+
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	records := []*Record{
+		{Time: start, Repo: "foo/bar", PR: 1, HeadSHA: "aaa", Decision: DecisionWait},
+		{Time: start.Add(time.Hour), Repo: "foo/bar", PR: 2, HeadSHA: "bbb", Decision: DecisionMerge, MergeSHA: "ccc"},
+		{Time: start.Add(2 * time.Hour), Repo: "foo/baz", PR: 1, HeadSHA: "ddd", Decision: DecisionEscalate, Error: "the milestone is missing"},
+	}
+
+	for _, rec := range records {
+		require.NoError(t, auditLog.Write(rec))
+	}
+
+	testCases := []struct {
+		desc     string
+		filter   Filter
+		expected []int
+	}{
+		{
+			desc:     "all",
+			expected: []int{0, 1, 2},
+		},
+		{
+			desc:     "repository",
+			filter:   Filter{Repo: "foo/bar"},
+			expected: []int{0, 1},
+		},
+		{
+			desc:     "pull request",
+			filter:   Filter{Repo: "foo/bar", PR: 2},
+			expected: []int{1},
+		},
+		{
+			desc:     "time range",
+			filter:   Filter{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)},
+			expected: []int{1},
+		},
+		{
+			desc:   "no match",
+			filter: Filter{Repo: "foo/qux"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+
+			t.Cleanup(func() { _ = file.Close() })
+
+			result, err := Query(file, test.filter)
+			require.NoError(t, err)
+
+			var expected []Record
+			for _, i := range test.expected {
+				expected = append(expected, *records[i])
+			}
+
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func TestLog_disabled(t *testing.T) {
+	auditLog := New(conf.Audit{})
+	require.Nil(t, auditLog)
+
+	assert.NoError(t, auditLog.Write(&Record{Repo: "foo/bar", PR: 1}))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, &Record{}, FromContext(context.Background()))
+
+	rec := &Record{}
+	ctx := NewContext(context.Background(), rec)
+
+	FromContext(ctx).AddGate("checks", true, "success")
+	FromContext(ctx).AddLabels("foo")
+	FromContext(ctx).RemoveLabels("bar")
+	FromContext(ctx).AddComment("hello")
+
+	expected := &Record{
+		Gates:         []Gate{{Name: "checks", Passed: true, Detail: "success"}},
+		LabelsAdded:   []string{"foo"},
+		LabelsRemoved: []string{"bar"},
+		Comments:      []string{"hello"},
+	}
+
+	assert.Equal(t, expected, rec)
+}
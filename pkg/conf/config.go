@@ -1,9 +1,12 @@
 package conf
 
 import (
+	"bytes"
 	"errors"
-	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -11,18 +14,50 @@ import (
 
 // Configuration the global configuration.
 type Configuration struct {
+	Forge        string                 `yaml:"forge,omitempty"`
 	Github       Github                 `yaml:"github"`
+	GitLab       GitLab                 `yaml:"gitlab"`
+	Gitea        Gitea                  `yaml:"gitea"`
+	Owners       []Owner                `yaml:"owners,omitempty"`
+	Filters      Filters                `yaml:"filters,omitempty"`
 	Git          Git                    `yaml:"git"`
 	Server       Server                 `yaml:"server"`
+	Daemon       Daemon                 `yaml:"daemon"`
 	Markers      Markers                `yaml:"markers"`
 	Retry        Retry                  `yaml:"retry"`
+	Priority     Priority               `yaml:"priority,omitempty"`
 	Default      RepoConfig             `yaml:"default"`
+	Tracing      Tracing                `yaml:"tracing,omitempty"`
+	Audit        Audit                  `yaml:"audit,omitempty"`
+	Notifiers    map[string]Notifier    `yaml:"notifiers,omitempty"`
 	Extra        Extra                  `yaml:"extra"`
 	Repositories map[string]*RepoConfig `yaml:"repositories,omitempty"`
 }
 
 // Github the GitHub configuration.
 type Github struct {
+	User  string    `yaml:"user,omitempty"`
+	Token string    `yaml:"token,omitempty"`
+	URL   string    `yaml:"url,omitempty"`
+	App   GitHubApp `yaml:"app,omitempty"`
+}
+
+// GitHubApp the GitHub App configuration.
+type GitHubApp struct {
+	ID int64 `yaml:"id,omitempty"`
+	// PrivateKey the path to the private key (PEM) of the app.
+	PrivateKey string `yaml:"privateKey,omitempty"`
+}
+
+// GitLab the GitLab configuration.
+type GitLab struct {
+	User  string `yaml:"user,omitempty"`
+	Token string `yaml:"token,omitempty"`
+	URL   string `yaml:"url,omitempty"`
+}
+
+// Gitea the Gitea (or Forgejo) configuration.
+type Gitea struct {
 	User  string `yaml:"user,omitempty"`
 	Token string `yaml:"token,omitempty"`
 	URL   string `yaml:"url,omitempty"`
@@ -30,14 +65,39 @@ type Github struct {
 
 // Git the Git configuration.
 type Git struct {
-	Email    string `yaml:"email,omitempty"`
-	UserName string `yaml:"userName,omitempty"`
-	SSH      bool   `yaml:"ssh,omitempty"`
+	Email    string   `yaml:"email,omitempty"`
+	UserName string   `yaml:"userName,omitempty"`
+	SSH      bool     `yaml:"ssh,omitempty"`
+	WorkDir  string   `yaml:"workDir,omitempty"`
+	Cache    GitCache `yaml:"cache,omitempty"`
+}
+
+// GitCache the configuration of the cache of repositories.
+type GitCache struct {
+	Dir     string        `yaml:"dir,omitempty"`
+	MaxAge  time.Duration `yaml:"maxAge,omitempty"`
+	MaxSize int           `yaml:"maxSize,omitempty"`
 }
 
 // Server the server configuration.
 type Server struct {
-	Port int `yaml:"port"`
+	Port          int       `yaml:"port"`
+	WebhookSecret string    `yaml:"webhookSecret,omitempty"`
+	Dashboard     Dashboard `yaml:"dashboard,omitempty"`
+}
+
+// Dashboard the dashboard configuration.
+type Dashboard struct {
+	// Enabled serves the dashboard (/dashboard and /dashboard.json).
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Token required to read the dashboard: a bearer token, or the password of a basic authentication (any user).
+	Token string `yaml:"token,omitempty"`
+}
+
+// Daemon the daemon configuration.
+type Daemon struct {
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Jitter   time.Duration `yaml:"jitter,omitempty"`
 }
 
 // Markers the markers configuration.
@@ -49,6 +109,7 @@ type Markers struct {
 	MergeRetryPrefix  string `yaml:"mergeRetryPrefix,omitempty"`
 	NeedHumanMerge    string `yaml:"needHumanMerge,omitempty"`
 	NoMerge           string `yaml:"noMerge,omitempty"`
+	MergeQueue        string `yaml:"mergeQueue,omitempty"`
 }
 
 // Retry the retry configuration.
@@ -59,26 +120,158 @@ type Retry struct {
 	OnStatuses  bool          `yaml:"onStatuses,omitempty"`
 }
 
+// Priority the order of the pull requests.
+type Priority struct {
+	// Labels the priority labels, from the highest priority (ex: bot/priority-critical, bot/priority-high).
+	Labels []string `yaml:"labels,omitempty"`
+	// LabeledAt orders the pull requests by the time of the addition of markers.needMerge, instead of the time of the last update.
+	LabeledAt bool `yaml:"labeledAt,omitempty"`
+}
+
+// Tracing the configuration of the traces (OpenTelemetry).
+type Tracing struct {
+	// Endpoint the URL of the OTLP (HTTP) endpoint (ex: http://localhost:4318/v1/traces), the traces are disabled if empty.
+	Endpoint    string `yaml:"endpoint,omitempty"`
+	ServiceName string `yaml:"serviceName,omitempty"`
+}
+
+// Audit the configuration of the audit log.
+type Audit struct {
+	// File the path of the audit log (JSON Lines), the audit log is disabled if empty.
+	File string `yaml:"file,omitempty"`
+}
+
+// Notifier a sink of the notifications, used by the repositories with its name (notify).
+type Notifier struct {
+	// Type slack, mattermost, teams, or webhook (the event as JSON).
+	Type string `yaml:"type,omitempty"`
+	URL  string `yaml:"url,omitempty"`
+	// Events the notified events, all the events if empty.
+	Events []string `yaml:"events,omitempty"`
+	// Template the template (text/template) of the message, not used by the type webhook.
+	Template string `yaml:"template,omitempty"`
+	// Handles the chat handles of the users by login on the forge (ex: alice: <@U012AB3CD>), not used by the type webhook.
+	Handles map[string]string `yaml:"handles,omitempty"`
+}
+
+// NotifierFuncs the functions available in the templates of the notifiers.
+var NotifierFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
 // Extra the extra configuration.
 type Extra struct {
-	DryRun   bool   `yaml:"dryRun,omitempty"`
-	LogLevel string `yaml:"logLevel,omitempty"`
+	DryRun      bool   `yaml:"dryRun,omitempty"`
+	LogLevel    string `yaml:"logLevel,omitempty"`
+	Concurrency int    `yaml:"concurrency,omitempty"`
+	// Debug deprecated: use LogLevel (debug).
+	Debug bool `yaml:"debug,omitempty"`
 }
 
-// Load loads the configuration.
-func Load(filename string) (Configuration, error) {
-	file, err := os.Open(filename)
+// Load loads and validates the configuration.
+// The precedence is: flags > environment variables > file > defaults.
+// The problems of the configuration are reported all at once, in a *ValidationError.
+func Load(filename string, flags Overrides) (Configuration, error) {
+	cfg, node, problems, err := read(filename, flags)
 	if err != nil {
 		return Configuration{}, err
 	}
 
-	cfg := Configuration{
-		Github: Github{
-			Token: os.Getenv("GITHUB_TOKEN"),
+	problems = append(problems, validate(cfg)...)
+	if len(problems) > 0 {
+		return Configuration{}, newValidationError(problems, node)
+	}
+
+	return cfg, nil
+}
+
+// Read reads the configuration, without validation.
+func Read(filename string, flags Overrides) (Configuration, error) {
+	cfg, _, _, err := read(filename, flags)
+	return cfg, err
+}
+
+// Deprecations gets the messages about the deprecated fields used by the configuration.
+func (c Configuration) Deprecations() []string {
+	var messages []string
+
+	if c.Extra.Debug {
+		messages = append(messages, "extra.debug is deprecated: use extra.logLevel: debug")
+	}
+
+	return messages
+}
+
+// read reads the configuration.
+// The unknown fields and the invalid types are returned as problems, with the rest of the configuration.
+func read(filename string, flags Overrides) (Configuration, *yaml.Node, []Problem, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Configuration{}, nil, nil, err
+	}
+
+	cfg := defaultConfiguration()
+	cfg.Github.Token = os.Getenv("GITHUB_TOKEN")
+	cfg.GitLab.Token = os.Getenv("GITLAB_TOKEN")
+	cfg.Gitea.Token = os.Getenv("GITEA_TOKEN")
+
+	var problems []Problem
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(&cfg)
+	if err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return Configuration{}, nil, nil, err
+		}
+
+		problems = decodeProblems(typeErr)
+	}
+
+	// the node is only used to find the lines of the problems.
+	node := &yaml.Node{}
+	_ = yaml.Unmarshal(data, node)
+
+	err = applyOverrides(&cfg, os.Environ(), flags)
+	if err != nil {
+		return Configuration{}, nil, nil, err
+	}
+
+	if cfg.Extra.Debug {
+		cfg.Extra.LogLevel = "debug"
+	}
+
+	for i := range cfg.Owners {
+		owner := &cfg.Owners[i]
+
+		owner.Markers = mergeMarkers(cfg.Markers, owner.Markers)
+
+		if owner.Default == nil {
+			owner.Default = &RepoConfig{}
+		}
+
+		applyDefault(owner.Default, cfg.Default)
+	}
+
+	return cfg, node, problems, nil
+}
+
+// defaultConfiguration the default values of the configuration.
+func defaultConfiguration() Configuration {
+	return Configuration{
+		Forge: ForgeGitHub,
+		GitLab: GitLab{
+			URL: "https://gitlab.com/api/v4",
 		},
 		Server: Server{
 			Port: 80,
 		},
+		Daemon: Daemon{
+			Interval: 5 * time.Minute,
+			Jitter:   30 * time.Second,
+		},
 		Markers: Markers{
 			LightReview:       "bot/light-review",
 			NeedMerge:         "status/3-needs-merge",
@@ -87,6 +280,7 @@ func Load(filename string) (Configuration, error) {
 			MergeRetryPrefix:  "bot/merge-retry-",
 			NeedHumanMerge:    "bot/need-human-merge",
 			NoMerge:           "bot/no-merge",
+			MergeQueue:        "bot/merge-queue",
 		},
 		Retry: Retry{
 			Interval: 1 * time.Minute,
@@ -100,100 +294,85 @@ func Load(filename string) (Configuration, error) {
 			ForceNeedUpToDate: Bool(true),
 			AddErrorInComment: Bool(false),
 			CommitMessage:     String("empty"),
+			MergeQueue:        Bool(false),
+			MergeQueueSize:    Int(5),
+		},
+		Tracing: Tracing{
+			ServiceName: "lobicornis",
 		},
 		Extra: Extra{
-			LogLevel: "info",
-			DryRun:   true,
+			LogLevel:    "info",
+			DryRun:      true,
+			Concurrency: 1,
 		},
 		Repositories: map[string]*RepoConfig{},
 	}
-	err = yaml.NewDecoder(file).Decode(&cfg)
-	if err != nil {
-		return Configuration{}, err
-	}
-
-	for _, config := range cfg.Repositories {
-		if config == nil {
-			continue
-		}
-
-		applyDefault(config, cfg)
-	}
-
-	err = validate(cfg)
-	if err != nil {
-		return Configuration{}, err
-	}
-
-	return cfg, nil
 }
 
-func applyDefault(config *RepoConfig, cfg Configuration) {
+func applyDefault(config *RepoConfig, def RepoConfig) {
 	if config.CheckNeedUpToDate == nil {
-		config.CheckNeedUpToDate = cfg.Default.CheckNeedUpToDate
+		config.CheckNeedUpToDate = def.CheckNeedUpToDate
 	}
 
 	if config.ForceNeedUpToDate == nil {
-		config.ForceNeedUpToDate = cfg.Default.ForceNeedUpToDate
+		config.ForceNeedUpToDate = def.ForceNeedUpToDate
 	}
 
 	if config.MergeMethod == nil {
-		config.MergeMethod = cfg.Default.MergeMethod
+		config.MergeMethod = def.MergeMethod
 	}
 
 	if config.MinLightReview == nil {
-		config.MinLightReview = cfg.Default.MinLightReview
+		config.MinLightReview = def.MinLightReview
 	}
 
 	if config.MinReview == nil {
-		config.MinReview = cfg.Default.MinReview
+		config.MinReview = def.MinReview
 	}
 
 	if config.NeedMilestone == nil {
-		config.NeedMilestone = cfg.Default.NeedMilestone
+		config.NeedMilestone = def.NeedMilestone
 	}
 
 	if config.AddErrorInComment == nil {
-		config.AddErrorInComment = cfg.Default.AddErrorInComment
+		config.AddErrorInComment = def.AddErrorInComment
 	}
 
 	if config.CommitMessage == nil {
-		config.CommitMessage = cfg.Default.CommitMessage
+		config.CommitMessage = def.CommitMessage
 	}
-}
 
-func validate(cfg Configuration) error {
-	fields := map[string]string{
-		"github.user":               cfg.Github.User,
-		"git.email":                 cfg.Git.Email,
-		"git.userName":              cfg.Git.UserName,
-		"markers.needMerge":         cfg.Markers.NeedMerge,
-		"markers.mergeInProgress":   cfg.Markers.MergeInProgress,
-		"markers.lightReview":       cfg.Markers.LightReview,
-		"markers.mergeMethodPrefix": cfg.Markers.MergeMethodPrefix,
-		"markers.needHumanMerge":    cfg.Markers.NeedHumanMerge,
-		"markers.noMerge":           cfg.Markers.NoMerge,
+	if config.MergeQueue == nil {
+		config.MergeQueue = def.MergeQueue
 	}
 
-	for field, value := range fields {
-		if value == "" {
-			return fmt.Errorf("%s is required", field)
-		}
+	if config.MergeQueueSize == nil {
+		config.MergeQueueSize = def.MergeQueueSize
 	}
 
-	if cfg.Default.GetMinReview() < 0 {
-		return errors.New("default.minReview is invalid")
+	if config.Notify == nil {
+		config.Notify = def.Notify
 	}
 
-	if cfg.Default.GetMinLightReview() < 0 {
-		return errors.New("default.minLightReview is invalid")
+	if config.Mentions == nil {
+		config.Mentions = def.Mentions
 	}
 
-	if cfg.Default.GetMergeMethod() == "" {
-		return errors.New("default.mergeMethod is required")
+	if config.MergeWindows == nil {
+		config.MergeWindows = def.MergeWindows
 	}
 
-	return nil
+	if config.Freezes == nil {
+		config.Freezes = def.Freezes
+	}
+
+	if config.TimeZone == nil {
+		config.TimeZone = def.TimeZone
+	}
+
+	if config.UpdateWhenFrozen == nil {
+		config.UpdateWhenFrozen = def.UpdateWhenFrozen
+	}
 }
 
 // String convert a string to a string pointer.
@@ -19,11 +19,15 @@ func TestLoad(t *testing.T) {
 			desc:     "simple",
 			filename: filepath.FromSlash("./fixtures/config.yml"),
 			expected: Configuration{
+				Forge: ForgeGitHub,
 				Github: Github{
 					User:  "ldez",
 					Token: "XXXX",
 					URL:   "http://my-private-github.com",
 				},
+				GitLab: GitLab{
+					URL: "https://gitlab.com/api/v4",
+				},
 				Git: Git{
 					Email:    "bot@example.com",
 					UserName: "botname",
@@ -32,6 +36,10 @@ func TestLoad(t *testing.T) {
 				Server: Server{
 					Port: 80,
 				},
+				Daemon: Daemon{
+					Interval: 10 * time.Minute,
+					Jitter:   1 * time.Minute,
+				},
 				Markers: Markers{
 					LightReview:       "bot/light-review",
 					NeedMerge:         "status/3-needs-merge",
@@ -40,6 +48,7 @@ func TestLoad(t *testing.T) {
 					MergeRetryPrefix:  "bot/merge-retry-",
 					NeedHumanMerge:    "bot/need-human-merge",
 					NoMerge:           "bot/no-merge",
+					MergeQueue:        "bot/merge-queue",
 				},
 				Retry: Retry{
 					Interval:    1 * time.Minute,
@@ -56,31 +65,28 @@ func TestLoad(t *testing.T) {
 					ForceNeedUpToDate: Bool(true),
 					AddErrorInComment: Bool(false),
 					CommitMessage:     String("empty"),
+					MergeQueue:        Bool(false),
+					MergeQueueSize:    Int(5),
+				},
+				Tracing: Tracing{
+					ServiceName: "lobicornis",
 				},
 				Extra: Extra{
-					DryRun:   true,
-					LogLevel: "info",
+					DryRun:      true,
+					LogLevel:    "info",
+					Concurrency: 1,
 				},
 				Repositories: map[string]*RepoConfig{
 					"ldez/myrepo1": {
-						MergeMethod:       String("squash"),
-						MinLightReview:    Int(1),
-						MinReview:         Int(0),
-						NeedMilestone:     Bool(true),
-						CheckNeedUpToDate: Bool(false),
-						ForceNeedUpToDate: Bool(true),
-						AddErrorInComment: Bool(false),
-						CommitMessage:     String("empty"),
+						MinLightReview: Int(1),
+						MinReview:      Int(0),
+						NeedMilestone:  Bool(true),
 					},
 					"ldez/myrepo2": {
-						MergeMethod:       String("squash"),
-						MinLightReview:    Int(1),
-						MinReview:         Int(1),
-						NeedMilestone:     Bool(false),
-						CheckNeedUpToDate: Bool(false),
-						ForceNeedUpToDate: Bool(true),
-						AddErrorInComment: Bool(false),
-						CommitMessage:     String("description"),
+						MinLightReview: Int(1),
+						MinReview:      Int(1),
+						NeedMilestone:  Bool(false),
+						CommitMessage:  String("description"),
 					},
 				},
 			},
@@ -89,11 +95,15 @@ func TestLoad(t *testing.T) {
 			desc:     "defaulting",
 			filename: filepath.FromSlash("./fixtures/config_01.yml"),
 			expected: Configuration{
+				Forge: ForgeGitHub,
 				Github: Github{
 					User:  "ldez",
 					Token: "XXXX",
 					URL:   "http://my-private-github.com",
 				},
+				GitLab: GitLab{
+					URL: "https://gitlab.com/api/v4",
+				},
 				Git: Git{
 					Email:    "bot@example.com",
 					UserName: "botname",
@@ -102,6 +112,10 @@ func TestLoad(t *testing.T) {
 				Server: Server{
 					Port: 80,
 				},
+				Daemon: Daemon{
+					Interval: 5 * time.Minute,
+					Jitter:   30 * time.Second,
+				},
 				Markers: Markers{
 					LightReview:       "bot/ooo",
 					NeedMerge:         "status/3-needs-merge",
@@ -110,6 +124,7 @@ func TestLoad(t *testing.T) {
 					MergeRetryPrefix:  "bot/merge-retry-",
 					NeedHumanMerge:    "bot/need-human-merge",
 					NoMerge:           "bot/no-merge",
+					MergeQueue:        "bot/merge-queue",
 				},
 				Retry: Retry{
 					Interval:    1 * time.Minute,
@@ -126,31 +141,144 @@ func TestLoad(t *testing.T) {
 					ForceNeedUpToDate: Bool(true),
 					AddErrorInComment: Bool(false),
 					CommitMessage:     String("empty"),
+					MergeQueue:        Bool(false),
+					MergeQueueSize:    Int(5),
+				},
+				Tracing: Tracing{
+					ServiceName: "lobicornis",
 				},
 				Extra: Extra{
-					DryRun:   true,
-					LogLevel: "info",
+					DryRun:      true,
+					LogLevel:    "info",
+					Concurrency: 1,
 				},
 				Repositories: map[string]*RepoConfig{
 					"ldez/myrepo1": {
-						MergeMethod:       String("squash"),
-						MinLightReview:    Int(25),
-						MinReview:         Int(0),
-						NeedMilestone:     Bool(true),
-						CheckNeedUpToDate: Bool(false),
-						ForceNeedUpToDate: Bool(true),
-						AddErrorInComment: Bool(false),
-						CommitMessage:     String("empty"),
+						MinReview:     Int(0),
+						NeedMilestone: Bool(true),
 					},
 					"ldez/myrepo2": {
-						MergeMethod:       String("squash"),
-						MinLightReview:    Int(1),
-						MinReview:         Int(1),
-						NeedMilestone:     Bool(false),
-						CheckNeedUpToDate: Bool(false),
-						ForceNeedUpToDate: Bool(true),
-						AddErrorInComment: Bool(false),
-						CommitMessage:     String("empty"),
+						MinLightReview: Int(1),
+						MinReview:      Int(1),
+						NeedMilestone:  Bool(false),
+					},
+				},
+			},
+		},
+		{
+			desc:     "owners",
+			filename: filepath.FromSlash("./fixtures/config_02.yml"),
+			expected: Configuration{
+				Forge: ForgeGitHub,
+				Github: Github{
+					Token: "XXXX",
+				},
+				GitLab: GitLab{
+					URL: "https://gitlab.com/api/v4",
+				},
+				Owners: []Owner{
+					{
+						Name: "ldez",
+						Markers: Markers{
+							LightReview:       "bot/light-review",
+							NeedMerge:         "status/3-needs-merge",
+							MergeInProgress:   "status/4-merge-in-progress",
+							MergeMethodPrefix: "bot/merge-method-",
+							MergeRetryPrefix:  "bot/merge-retry-",
+							NeedHumanMerge:    "bot/need-human-merge",
+							NoMerge:           "bot/no-merge",
+							MergeQueue:        "bot/merge-queue",
+						},
+						Default: &RepoConfig{
+							MergeMethod:       String("squash"),
+							MinLightReview:    Int(25),
+							MinReview:         Int(1),
+							NeedMilestone:     Bool(true),
+							CheckNeedUpToDate: Bool(false),
+							ForceNeedUpToDate: Bool(true),
+							AddErrorInComment: Bool(false),
+							CommitMessage:     String("empty"),
+							MergeQueue:        Bool(false),
+							MergeQueueSize:    Int(5),
+						},
+					},
+					{
+						Name:  "traefik",
+						Token: "YYYY",
+						Markers: Markers{
+							LightReview:       "bot/light-review",
+							NeedMerge:         "bot/merge",
+							MergeInProgress:   "status/4-merge-in-progress",
+							MergeMethodPrefix: "bot/merge-method-",
+							MergeRetryPrefix:  "bot/merge-retry-",
+							NeedHumanMerge:    "bot/need-human-merge",
+							NoMerge:           "bot/no-merge",
+							MergeQueue:        "bot/merge-queue",
+						},
+						Default: &RepoConfig{
+							MergeMethod:       String("squash"),
+							MinLightReview:    Int(25),
+							MinReview:         Int(2),
+							NeedMilestone:     Bool(true),
+							CheckNeedUpToDate: Bool(false),
+							ForceNeedUpToDate: Bool(true),
+							AddErrorInComment: Bool(false),
+							CommitMessage:     String("empty"),
+							MergeQueue:        Bool(false),
+							MergeQueueSize:    Int(5),
+						},
+					},
+				},
+				Git: Git{
+					Email:    "bot@example.com",
+					UserName: "botname",
+				},
+				Server: Server{
+					Port: 80,
+				},
+				Daemon: Daemon{
+					Interval: 5 * time.Minute,
+					Jitter:   30 * time.Second,
+				},
+				Markers: Markers{
+					LightReview:       "bot/light-review",
+					NeedMerge:         "status/3-needs-merge",
+					MergeInProgress:   "status/4-merge-in-progress",
+					MergeMethodPrefix: "bot/merge-method-",
+					MergeRetryPrefix:  "bot/merge-retry-",
+					NeedHumanMerge:    "bot/need-human-merge",
+					NoMerge:           "bot/no-merge",
+					MergeQueue:        "bot/merge-queue",
+				},
+				Retry: Retry{
+					Interval: 1 * time.Minute,
+				},
+				Default: RepoConfig{
+					MergeMethod:       String("squash"),
+					MinLightReview:    Int(25),
+					MinReview:         Int(1),
+					NeedMilestone:     Bool(true),
+					CheckNeedUpToDate: Bool(false),
+					ForceNeedUpToDate: Bool(true),
+					AddErrorInComment: Bool(false),
+					CommitMessage:     String("empty"),
+					MergeQueue:        Bool(false),
+					MergeQueueSize:    Int(5),
+				},
+				Tracing: Tracing{
+					ServiceName: "lobicornis",
+				},
+				Extra: Extra{
+					DryRun:      true,
+					LogLevel:    "info",
+					Concurrency: 1,
+				},
+				Repositories: map[string]*RepoConfig{
+					"traefik/traefik": {
+						NeedMilestone: Bool(false),
+					},
+					"ldez/myrepo": {
+						MinReview: Int(0),
 					},
 				},
 			},
@@ -162,10 +290,18 @@ func TestLoad(t *testing.T) {
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
 
-			cfg, err := Load(test.filename)
+			cfg, err := Load(test.filename, nil)
 			require.NoError(t, err)
 
 			assert.Equal(t, test.expected, cfg)
 		})
 	}
 }
+
+func TestLoad_deprecatedDebug(t *testing.T) {
+	cfg, err := Load(filepath.FromSlash("./fixtures/config_debug.yml"), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "debug", cfg.Extra.LogLevel)
+	assert.Equal(t, []string{"extra.debug is deprecated: use extra.logLevel: debug"}, cfg.Deprecations())
+}
@@ -7,3 +7,37 @@ const (
 	MergeMethodMerge       = "merge"
 	MergeMethodFastForward = "ff"
 )
+
+// MaxConcurrency the maximum number of repositories processed concurrently.
+// Higher values trigger the GitHub secondary rate limits.
+const MaxConcurrency = 10
+
+// Forges.
+const (
+	ForgeGitHub = "github"
+	ForgeGitLab = "gitlab"
+	ForgeGitea  = "gitea"
+)
+
+// Notifier types.
+const (
+	NotifierSlack      = "slack"
+	NotifierMattermost = "mattermost"
+	NotifierTeams      = "teams"
+	NotifierWebhook    = "webhook"
+)
+
+// Notification events.
+const (
+	EventMerged         = "merged"
+	EventUpdated        = "updated"
+	EventEscalated      = "escalated"
+	EventRetryExhausted = "retryExhausted"
+)
+
+// RepoConfigFile the path of the configuration file inside a repository.
+const RepoConfigFile = ".github/lobicornis.yml"
+
+// FreezeFile the path of the file inside a repository which freezes the merges (emergency freeze).
+// The content of the file is the reason of the freeze.
+const FreezeFile = ".github/lobicornis.freeze"
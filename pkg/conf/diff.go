@@ -0,0 +1,155 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+// Redact returns a copy of the configuration without the secrets.
+func (c Configuration) Redact() Configuration {
+	cfg := c
+
+	cfg.Github.Token = redact(c.Github.Token)
+	cfg.GitLab.Token = redact(c.GitLab.Token)
+	cfg.Gitea.Token = redact(c.Gitea.Token)
+	cfg.Server.WebhookSecret = redact(c.Server.WebhookSecret)
+	cfg.Server.Dashboard.Token = redact(c.Server.Dashboard.Token)
+
+	if c.Notifiers != nil {
+		cfg.Notifiers = make(map[string]Notifier, len(c.Notifiers))
+		for name, notifier := range c.Notifiers {
+			notifier.URL = redact(notifier.URL)
+			cfg.Notifiers[name] = notifier
+		}
+	}
+
+	if c.Owners != nil {
+		cfg.Owners = make([]Owner, len(c.Owners))
+		for i, owner := range c.Owners {
+			owner.Token = redact(owner.Token)
+			cfg.Owners[i] = owner
+		}
+	}
+
+	return cfg
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// Diff lists the fields that differ between 2 configurations (ex: `default.minReview: 1 -> 2`).
+// The secrets are redacted: a changed secret is displayed as changed, without its values.
+func Diff(old, current Configuration) []string {
+	oldFields := make(map[string]string)
+	flatten("", reflect.ValueOf(old), oldFields)
+
+	currentFields := make(map[string]string)
+	flatten("", reflect.ValueOf(current), currentFields)
+
+	oldRedacted := make(map[string]string)
+	flatten("", reflect.ValueOf(old.Redact()), oldRedacted)
+
+	currentRedacted := make(map[string]string)
+	flatten("", reflect.ValueOf(current.Redact()), currentRedacted)
+
+	names := make(map[string]struct{})
+	for name := range oldFields {
+		names[name] = struct{}{}
+	}
+	for name := range currentFields {
+		names[name] = struct{}{}
+	}
+
+	var lines []string
+	for name := range names {
+		oldValue, oldOk := oldFields[name]
+		currentValue, currentOk := currentFields[name]
+
+		if oldOk == currentOk && oldValue == currentValue {
+			continue
+		}
+
+		// a secret is redacted in one of the configurations.
+		if oldRedacted[name] == redacted || currentRedacted[name] == redacted {
+			lines = append(lines, fmt.Sprintf("%s: %s (changed)", name, redacted))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s", name, displayValue(oldValue, oldOk), displayValue(currentValue, currentOk)))
+	}
+
+	sort.Strings(lines)
+
+	return lines
+}
+
+func displayValue(value string, ok bool) string {
+	if !ok {
+		return "(none)"
+	}
+
+	return fmt.Sprintf("%q", value)
+}
+
+// flatten lists the leaf values of a configuration by path, the names of the fields are the YAML names.
+func flatten(path string, value reflect.Value, fields map[string]string) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			flatten(path, value.Elem(), fields)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			flatten(joinPath(path, name), value.Field(i), fields)
+		}
+
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			flatten(joinPath(path, fmt.Sprint(key.Interface())), value.MapIndex(key), fields)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			flatten(fmt.Sprintf("%s[%d]", path, i), value.Index(i), fields)
+		}
+
+	default:
+		if stringer, ok := value.Interface().(fmt.Stringer); ok {
+			fields[path] = stringer.String()
+			return
+		}
+
+		fields[path] = fmt.Sprint(value.Interface())
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
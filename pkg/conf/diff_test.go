@@ -0,0 +1,73 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := Configuration{
+		Github:  Github{User: "foo", Token: "secret"},
+		Daemon:  Daemon{Interval: 5 * time.Minute},
+		Default: RepoConfig{MinReview: Int(1)},
+		Repositories: map[string]*RepoConfig{
+			"foo/bar": {NeedMilestone: Bool(true)},
+		},
+	}
+
+	current := Configuration{
+		Github:  Github{User: "foo", Token: "other"},
+		Daemon:  Daemon{Interval: 10 * time.Minute},
+		Default: RepoConfig{MinReview: Int(2)},
+		Repositories: map[string]*RepoConfig{
+			"foo/*": {MinReview: Int(3)},
+		},
+	}
+
+	expected := []string{
+		`daemon.interval: "5m0s" -> "10m0s"`,
+		`default.minReview: "1" -> "2"`,
+		`github.token: [redacted] (changed)`,
+		`repositories.foo/*.minReview: (none) -> "3"`,
+		`repositories.foo/bar.needMilestone: "true" -> (none)`,
+	}
+
+	assert.Equal(t, expected, Diff(old, current))
+}
+
+func TestDiff_unchanged(t *testing.T) {
+	cfg := Configuration{
+		Github:  Github{User: "foo", Token: "secret"},
+		Default: RepoConfig{MinReview: Int(1)},
+	}
+
+	assert.Empty(t, Diff(cfg, cfg))
+}
+
+func TestConfiguration_Redact(t *testing.T) {
+	cfg := Configuration{
+		Github: Github{User: "foo", Token: "secret"},
+		Owners: []Owner{{Name: "foo"}, {Name: "bar", Token: "secret"}},
+		Server: Server{WebhookSecret: "secret"},
+		Notifiers: map[string]Notifier{
+			"team": {Type: NotifierSlack, URL: "https://hooks.slack.com/services/secret"},
+		},
+	}
+
+	expected := Configuration{
+		Github: Github{User: "foo", Token: redacted},
+		Owners: []Owner{{Name: "foo"}, {Name: "bar", Token: redacted}},
+		Server: Server{WebhookSecret: redacted},
+		Notifiers: map[string]Notifier{
+			"team": {Type: NotifierSlack, URL: redacted},
+		},
+	}
+
+	assert.Equal(t, expected, cfg.Redact())
+
+	// the original configuration is unchanged.
+	assert.Equal(t, "secret", cfg.Owners[1].Token)
+	assert.Equal(t, "https://hooks.slack.com/services/secret", cfg.Notifiers["team"].URL)
+}
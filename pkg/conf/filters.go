@@ -0,0 +1,53 @@
+package conf
+
+import (
+	"path"
+	"strings"
+)
+
+// Filters the selection of the repositories.
+type Filters struct {
+	// Include the patterns (glob on owner/name) of the repositories managed by the bot (all by default).
+	Include []string `yaml:"include,omitempty"`
+	// Exclude the patterns (glob on owner/name) of the repositories ignored by the bot.
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Topics a repository must have at least one of these topics.
+	Topics []string `yaml:"topics,omitempty"`
+}
+
+// MatchName checks if a repository (full name) is selected by the include and exclude patterns.
+func (f Filters) MatchName(fullName string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, fullName) {
+		return false
+	}
+
+	return !matchAny(f.Exclude, fullName)
+}
+
+// MatchTopics checks if a repository has one of the topics.
+func (f Filters) MatchTopics(topics []string) bool {
+	if len(f.Topics) == 0 {
+		return true
+	}
+
+	for _, topic := range topics {
+		for _, expected := range f.Topics {
+			if strings.EqualFold(topic, expected) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchAny(patterns []string, fullName string) bool {
+	for _, pattern := range patterns {
+		// the patterns are validated when the configuration is loaded.
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(fullName)); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters_MatchName(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		filters  Filters
+		fullName string
+		expected bool
+	}{
+		{
+			desc:     "no filters",
+			fullName: "traefik/traefik",
+			expected: true,
+		},
+		{
+			desc:     "included",
+			filters:  Filters{Include: []string{"traefik/*"}},
+			fullName: "traefik/traefik",
+			expected: true,
+		},
+		{
+			desc:     "not included",
+			filters:  Filters{Include: []string{"traefik/*"}},
+			fullName: "containous/lobicornis",
+			expected: false,
+		},
+		{
+			desc:     "excluded",
+			filters:  Filters{Exclude: []string{"traefik/*-legacy"}},
+			fullName: "traefik/mesh-legacy",
+			expected: false,
+		},
+		{
+			desc:     "included and excluded",
+			filters:  Filters{Include: []string{"traefik/*"}, Exclude: []string{"traefik/mesh*"}},
+			fullName: "traefik/mesh",
+			expected: false,
+		},
+		{
+			desc:     "case insensitive",
+			filters:  Filters{Include: []string{"Traefik/*"}},
+			fullName: "traefik/Traefik",
+			expected: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.filters.MatchName(test.fullName))
+		})
+	}
+}
+
+func TestFilters_MatchTopics(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		filters  Filters
+		topics   []string
+		expected bool
+	}{
+		{
+			desc:     "no filters",
+			expected: true,
+		},
+		{
+			desc:     "one of the topics",
+			filters:  Filters{Topics: []string{"bot", "lobicornis"}},
+			topics:   []string{"go", "Lobicornis"},
+			expected: true,
+		},
+		{
+			desc:     "none of the topics",
+			filters:  Filters{Topics: []string{"bot"}},
+			topics:   []string{"go"},
+			expected: false,
+		},
+		{
+			desc:     "no topics",
+			filters:  Filters{Topics: []string{"bot"}},
+			expected: false,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.filters.MatchTopics(test.topics))
+		})
+	}
+}
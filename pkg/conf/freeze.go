@@ -0,0 +1,185 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayout the layout of the days of the freezes.
+const dateLayout = "2006-01-02"
+
+// MergeWindow a period allowed for the merges.
+type MergeWindow struct {
+	// Days the days of the week (mon, tue, wed, thu, fri, sat, sun), every day if empty.
+	Days []string `yaml:"days,omitempty"`
+	// Hours the range of hours (ex: 09:00-18:00, 22:00-06:00 for an overnight window), the whole day if empty.
+	Hours string `yaml:"hours,omitempty"`
+}
+
+// Freeze a period without merge (ex: holidays, release).
+type Freeze struct {
+	// From the first day of the freeze (ex: 2021-12-20).
+	From string `yaml:"from"`
+	// To the last day of the freeze, the day of From if empty.
+	To string `yaml:"to,omitempty"`
+	// Reason the reason of the freeze.
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// GetTimeZone gets TimeZone.
+func (r *RepoConfig) GetTimeZone() string {
+	if r.TimeZone != nil {
+		return *r.TimeZone
+	}
+
+	return "UTC"
+}
+
+// GetUpdateWhenFrozen gets UpdateWhenFrozen.
+func (r *RepoConfig) GetUpdateWhenFrozen() bool {
+	if r.UpdateWhenFrozen != nil {
+		return *r.UpdateWhenFrozen
+	}
+
+	return false
+}
+
+// MergeFrozen checks the merge windows and the freezes at a time.
+// Returns the reason if the merges are not allowed, else an empty string.
+// The central merge windows and freezes apply before the ones of the configuration file of the repository.
+func (r *RepoConfig) MergeFrozen(now time.Time) (string, error) {
+	if r.bound != nil {
+		reason, err := r.bound.MergeFrozen(now)
+		if err != nil || reason != "" {
+			return reason, err
+		}
+	}
+
+	loc, err := time.LoadLocation(r.GetTimeZone())
+	if err != nil {
+		return "", fmt.Errorf("unable to load the time zone of the merge windows: %w", err)
+	}
+
+	now = now.In(loc)
+
+	for _, freeze := range r.Freezes {
+		if freeze.contains(now) {
+			reason := "freeze " + freeze.From
+			if freeze.To != "" && freeze.To != freeze.From {
+				reason += " to " + freeze.To
+			}
+
+			if freeze.Reason != "" {
+				reason += ": " + freeze.Reason
+			}
+
+			return reason, nil
+		}
+	}
+
+	if len(r.MergeWindows) == 0 {
+		return "", nil
+	}
+
+	for _, window := range r.MergeWindows {
+		if window.contains(now) {
+			return "", nil
+		}
+	}
+
+	return fmt.Sprintf("outside the merge windows (%s)", now.Format("Mon 15:04 MST")), nil
+}
+
+func (w MergeWindow) contains(t time.Time) bool {
+	if w.Hours == "" {
+		return w.hasDay(t.Weekday())
+	}
+
+	start, end, err := parseHours(w.Hours)
+	if err != nil {
+		return false
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return w.hasDay(t.Weekday()) && start <= minutes && minutes < end
+	}
+
+	// an overnight window: the hours after midnight belong to the window of the previous day.
+	if minutes >= start {
+		return w.hasDay(t.Weekday())
+	}
+
+	return minutes < end && w.hasDay((t.Weekday()+6)%7)
+}
+
+// hasDay checks if the window starts on a day of the week.
+func (w MergeWindow) hasDay(day time.Weekday) bool {
+	return len(w.Days) == 0 || contains(w.Days, weekdays[day])
+}
+
+func (f Freeze) contains(t time.Time) bool {
+	from, to, err := f.days(t.Location())
+	if err != nil {
+		return false
+	}
+
+	return !t.Before(from) && t.Before(to)
+}
+
+// days gets the beginning of the first day, and the end of the last day of the freeze.
+func (f Freeze) days(loc *time.Location) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation(dateLayout, f.From, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if f.To == "" {
+		return from, from.AddDate(0, 0, 1), nil
+	}
+
+	to, err := time.ParseInLocation(dateLayout, f.To, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// parseHours parses a range of hours (ex: 09:00-18:00) to minutes since midnight, the end is excluded.
+// The end is before the start for an overnight range (ex: 22:00-06:00).
+func parseHours(value string) (int, int, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid range of hours %q (ex: 09:00-18:00)", value)
+	}
+
+	start, err := parseClock(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := parseClock(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if start == end {
+		return 0, 0, fmt.Errorf("the range of hours %q is empty", value)
+	}
+
+	return start, end, nil
+}
+
+// parseClock parses a time of the day (ex: 09:00, 24:00) to minutes since midnight.
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	_, err := fmt.Sscanf(value, "%d:%d", &hour, &minute)
+	if err != nil || len(value) != 5 || hour < 0 || minute < 0 || minute > 59 || hour > 24 || hour == 24 && minute > 0 {
+		return 0, fmt.Errorf("invalid time %q (ex: 09:00)", value)
+	}
+
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,151 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoConfig_MergeFrozen(t *testing.T) {
+	config := RepoConfig{
+		MergeWindows: []MergeWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Hours: "09:00-18:00"},
+			{Days: []string{"sat"}, Hours: "10:00-12:00"},
+			{Days: []string{"sun"}, Hours: "22:00-06:00"},
+		},
+		Freezes: []Freeze{
+			{From: "2021-12-20", To: "2022-01-02", Reason: "holidays"},
+			{From: "2021-03-04"},
+		},
+		TimeZone: String("Europe/Paris"),
+	}
+
+	// without merge windows: only the freezes apply.
+	freezes := RepoConfig{
+		Freezes:  config.Freezes,
+		TimeZone: config.TimeZone,
+	}
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	testCases := []struct {
+		desc     string
+		config   RepoConfig
+		now      time.Time
+		expected string
+	}{
+		{
+			desc: "no window",
+			now:  time.Date(2021, time.March, 7, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:   "inside a window",
+			config: config,
+			now:    time.Date(2021, time.March, 1, 9, 0, 0, 0, paris),
+		},
+		{
+			desc:   "inside a window (time zone)",
+			config: config,
+			now:    time.Date(2021, time.March, 1, 16, 59, 0, 0, time.UTC),
+		},
+		{
+			desc:     "end of a window",
+			config:   config,
+			now:      time.Date(2021, time.March, 1, 18, 0, 0, 0, paris),
+			expected: "outside the merge windows (Mon 18:00 CET)",
+		},
+		{
+			desc:     "outside the days",
+			config:   config,
+			now:      time.Date(2021, time.March, 7, 11, 0, 0, 0, paris),
+			expected: "outside the merge windows (Sun 11:00 CET)",
+		},
+		{
+			desc:   "inside the other window",
+			config: config,
+			now:    time.Date(2021, time.March, 6, 11, 0, 0, 0, paris),
+		},
+		{
+			desc:   "overnight window before midnight",
+			config: config,
+			now:    time.Date(2021, time.March, 7, 23, 0, 0, 0, paris),
+		},
+		{
+			desc:   "overnight window after midnight",
+			config: config,
+			now:    time.Date(2021, time.March, 8, 5, 59, 0, 0, paris),
+		},
+		{
+			desc:     "end of an overnight window",
+			config:   config,
+			now:      time.Date(2021, time.March, 8, 6, 0, 0, 0, paris),
+			expected: "outside the merge windows (Mon 06:00 CET)",
+		},
+		{
+			desc:     "overnight window only after the days",
+			config:   config,
+			now:      time.Date(2021, time.March, 7, 3, 0, 0, 0, paris),
+			expected: "outside the merge windows (Sun 03:00 CET)",
+		},
+		{
+			desc:     "freeze of one day",
+			config:   config,
+			now:      time.Date(2021, time.March, 4, 10, 0, 0, 0, paris),
+			expected: "freeze 2021-03-04",
+		},
+		{
+			desc:     "last day of a freeze",
+			config:   config,
+			now:      time.Date(2021, time.December, 31, 23, 0, 0, 0, time.UTC),
+			expected: "freeze 2021-12-20 to 2022-01-02: holidays",
+		},
+		{
+			desc:     "last minute of a freeze",
+			config:   freezes,
+			now:      time.Date(2022, time.January, 2, 23, 59, 0, 0, paris),
+			expected: "freeze 2021-12-20 to 2022-01-02: holidays",
+		},
+		{
+			desc:   "first minute after a freeze",
+			config: freezes,
+			now:    time.Date(2022, time.January, 3, 0, 0, 0, 0, paris),
+		},
+		{
+			desc:   "first minute after a freeze (time zone)",
+			config: freezes,
+			now:    time.Date(2022, time.January, 2, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:   "after a freeze",
+			config: config,
+			now:    time.Date(2022, time.January, 3, 10, 0, 0, 0, paris),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reason, err := test.config.MergeFrozen(test.now)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, reason)
+		})
+	}
+}
+
+func TestRepoConfig_MergeFrozen_invalidTimeZone(t *testing.T) {
+	config := RepoConfig{
+		MergeWindows: []MergeWindow{{Hours: "09:00-18:00"}},
+		TimeZone:     String("Europe/Nowhere"),
+	}
+
+	_, err := config.MergeFrozen(time.Now())
+	require.Error(t, err)
+}
@@ -0,0 +1,200 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix the prefix of the environment variables overriding the configuration.
+const EnvPrefix = "LOBICORNIS_"
+
+// Overrides the values overriding the configuration, by field path (ex: retry.number).
+type Overrides map[string]string
+
+// Fields lists the paths of the fields that can be overridden (ex: retry.number).
+// The owners and the repositories cannot be overridden.
+func Fields() []string {
+	var paths []string
+
+	walkFields("", reflect.ValueOf(&Configuration{}).Elem(), func(path string, _ reflect.Value) {
+		paths = append(paths, path)
+	})
+
+	return paths
+}
+
+// EnvName gets the name of the environment variable of a field (ex: retry.number -> LOBICORNIS_RETRY_NUMBER).
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// applyOverrides applies the environment variables, then the flags.
+func applyOverrides(cfg *Configuration, environ []string, flags Overrides) error {
+	env := make(map[string]string)
+	for _, entry := range environ {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], EnvPrefix) {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	var err error
+	walkFields("", reflect.ValueOf(cfg).Elem(), func(path string, field reflect.Value) {
+		if err != nil {
+			return
+		}
+
+		if value, ok := env[EnvName(path)]; ok {
+			err = setField(field, value)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", EnvName(path), err)
+				return
+			}
+		}
+
+		if value, ok := flags[path]; ok {
+			err = setField(field, value)
+			if err != nil {
+				err = fmt.Errorf("-%s: %w", path, err)
+			}
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fields := Fields()
+
+	// the typos in the names of the environment variables are reported like the typos in the flags.
+	envNames := make(map[string]struct{}, len(fields))
+	for _, path := range fields {
+		envNames[EnvName(path)] = struct{}{}
+	}
+
+	var unknown []string
+	for name := range env {
+		if _, ok := envNames[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown environment variable: %s", strings.Join(unknown, ", "))
+	}
+
+	for path := range flags {
+		if !containsPath(fields, path) {
+			return fmt.Errorf("unknown field: %s", path)
+		}
+	}
+
+	return nil
+}
+
+// walkFields calls fn for each leaf field of the struct, the names of the fields are the YAML names.
+func walkFields(path string, value reflect.Value, fn func(path string, field reflect.Value)) {
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		field := value.Field(i)
+		fieldPath := joinPath(path, name)
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			walkFields(fieldPath, field, fn)
+		case isLeaf(field.Type()):
+			fn(fieldPath, field)
+		}
+	}
+}
+
+func isLeaf(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.String
+	default:
+		return false
+	}
+}
+
+func setField(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.Ptr {
+		value := reflect.New(field.Type().Elem())
+
+		err := setField(value.Elem(), raw)
+		if err != nil {
+			return err
+		}
+
+		field.Set(value)
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(value)
+
+	case reflect.Int, reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			value, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q", raw)
+			}
+			field.SetInt(int64(value))
+
+			return nil
+		}
+
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(value)
+
+	case reflect.Slice:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	}
+
+	return nil
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+
+	return false
+}
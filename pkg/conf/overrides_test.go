@@ -0,0 +1,96 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyOverrides(t *testing.T) {
+	cfg := Configuration{
+		Github: Github{User: "foo"},
+		Retry:  Retry{Number: 1},
+		Default: RepoConfig{
+			MergeMethod: String("squash"),
+		},
+	}
+
+	environ := []string{
+		"LOBICORNIS_RETRY_NUMBER=2",
+		"LOBICORNIS_RETRY_INTERVAL=2m",
+		"LOBICORNIS_DEFAULT_MERGEMETHOD=rebase",
+		"LOBICORNIS_DEFAULT_MINREVIEW=3",
+		"LOBICORNIS_FILTERS_INCLUDE=foo/*, bar/*",
+		"GITHUB_USER=bar",
+	}
+
+	flags := Overrides{
+		"retry.number":          "4",
+		"github.app.id":         "123",
+		"extra.dryRun":          "false",
+		"default.needMilestone": "false",
+	}
+
+	err := applyOverrides(&cfg, environ, flags)
+	require.NoError(t, err)
+
+	expected := Configuration{
+		Github: Github{User: "foo", App: GitHubApp{ID: 123}},
+		Filters: Filters{
+			Include: []string{"foo/*", "bar/*"},
+		},
+		Retry: Retry{Number: 4, Interval: 2 * time.Minute},
+		Default: RepoConfig{
+			MergeMethod:   String("rebase"),
+			MinReview:     Int(3),
+			NeedMilestone: Bool(false),
+		},
+	}
+
+	assert.Equal(t, expected, cfg)
+}
+
+func Test_applyOverrides_errors(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		environ []string
+		flags   Overrides
+	}{
+		{
+			desc:    "invalid integer",
+			environ: []string{"LOBICORNIS_RETRY_NUMBER=two"},
+		},
+		{
+			desc:  "invalid duration",
+			flags: Overrides{"daemon.interval": "5"},
+		},
+		{
+			desc:  "invalid boolean",
+			flags: Overrides{"extra.dryRun": "maybe"},
+		},
+		{
+			desc:  "unknown field",
+			flags: Overrides{"retry.numbers": "2"},
+		},
+		{
+			desc:    "unknown environment variable",
+			environ: []string{"LOBICORNIS_RETRY_NUMBERS=2"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := applyOverrides(&Configuration{}, test.environ, test.flags)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "LOBICORNIS_GITHUB_APP_PRIVATEKEY", EnvName("github.app.privateKey"))
+}
@@ -0,0 +1,85 @@
+package conf
+
+import "strings"
+
+// Owner an owner (user, organization, or group) of repositories, with its overrides.
+type Owner struct {
+	Name string `yaml:"name"`
+	// Token overrides the token of the forge.
+	Token string `yaml:"token,omitempty"`
+	// Markers overrides the global markers (only the defined markers).
+	Markers Markers `yaml:"markers,omitempty"`
+	// Default overrides the default configuration of the repositories of the owner.
+	Default *RepoConfig `yaml:"default,omitempty"`
+}
+
+// GetOwners gets the owners managed by the bot.
+// Without owners, the only owner is the user of the forge.
+func (c Configuration) GetOwners() []Owner {
+	if len(c.Owners) > 0 {
+		return c.Owners
+	}
+
+	def := c.Default
+
+	return []Owner{{Name: c.getForgeUser(), Markers: c.Markers, Default: &def}}
+}
+
+// GetOwner gets the owner of a repository (full name).
+func (c Configuration) GetOwner(fullName string) (Owner, bool) {
+	name := fullName
+	if index := strings.LastIndex(fullName, "/"); index >= 0 {
+		name = fullName[:index]
+	}
+
+	for _, owner := range c.GetOwners() {
+		if strings.EqualFold(owner.Name, name) {
+			return owner, true
+		}
+	}
+
+	return Owner{}, false
+}
+
+// GetDefault gets the default configuration of a repository (full name): the default configuration of its owner, or the global one.
+func (c Configuration) GetDefault(fullName string) RepoConfig {
+	owner, ok := c.GetOwner(fullName)
+	if !ok || owner.Default == nil {
+		return c.Default
+	}
+
+	return *owner.Default
+}
+
+func (c Configuration) getForgeUser() string {
+	switch c.Forge {
+	case ForgeGitLab:
+		return c.GitLab.User
+	case ForgeGitea:
+		return c.Gitea.User
+	default:
+		return c.Github.User
+	}
+}
+
+// mergeMarkers overrides the markers by the defined markers.
+func mergeMarkers(markers, overrides Markers) Markers {
+	merged := markers
+
+	set := func(value *string, override string) {
+		if override != "" {
+			*value = override
+		}
+	}
+
+	set(&merged.LightReview, overrides.LightReview)
+	set(&merged.NeedMerge, overrides.NeedMerge)
+	set(&merged.MergeInProgress, overrides.MergeInProgress)
+	set(&merged.MergeMethodPrefix, overrides.MergeMethodPrefix)
+	set(&merged.MergeRetryPrefix, overrides.MergeRetryPrefix)
+	set(&merged.NeedHumanMerge, overrides.NeedHumanMerge)
+	set(&merged.NoMerge, overrides.NoMerge)
+	set(&merged.MergeQueue, overrides.MergeQueue)
+
+	return merged
+}
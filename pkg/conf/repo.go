@@ -10,6 +10,23 @@ type RepoConfig struct {
 	ForceNeedUpToDate *bool   `yaml:"forceNeedUpToDate,omitempty"`
 	AddErrorInComment *bool   `yaml:"addErrorInComment,omitempty"`
 	CommitMessage     *string `yaml:"commitMessage,omitempty"`
+	MergeQueue        *bool   `yaml:"mergeQueue,omitempty"`
+	MergeQueueSize    *int    `yaml:"mergeQueueSize,omitempty"`
+	// Notify the names of the notifiers of the repository.
+	Notify []string `yaml:"notify,omitempty"`
+	// Mentions the users mentioned in the notifications (ex: the on-duty maintainer).
+	Mentions []string `yaml:"mentions,omitempty"`
+	// MergeWindows the periods allowed for the merges, always allowed if empty.
+	MergeWindows []MergeWindow `yaml:"mergeWindows,omitempty"`
+	// Freezes the periods without merge, even inside the merge windows.
+	Freezes []Freeze `yaml:"freezes,omitempty"`
+	// TimeZone the IANA time zone of the merge windows and the freezes (ex: Europe/Paris).
+	TimeZone *string `yaml:"timeZone,omitempty"`
+	// UpdateWhenFrozen allows the updates of the branches when the merges are frozen.
+	UpdateWhenFrozen *bool `yaml:"updateWhenFrozen,omitempty"`
+
+	// bound the central merge windows and freezes, when the configuration file of the repository defines its own.
+	bound *RepoConfig
 }
 
 // GetMergeMethod gets merge method.
@@ -83,3 +100,21 @@ func (r *RepoConfig) GetCommitMessage() string {
 
 	return ""
 }
+
+// GetMergeQueue gets MergeQueue.
+func (r *RepoConfig) GetMergeQueue() bool {
+	if r.MergeQueue != nil {
+		return *r.MergeQueue
+	}
+
+	return false
+}
+
+// GetMergeQueueSize gets MergeQueueSize.
+func (r *RepoConfig) GetMergeQueueSize() int {
+	if r.MergeQueueSize != nil {
+		return *r.MergeQueueSize
+	}
+
+	return -1
+}
@@ -0,0 +1,238 @@
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoPattern a pattern used as a key of the repositories configuration.
+type repoPattern struct {
+	key    string
+	exp    *regexp.Regexp
+	glob   string
+	weight int
+}
+
+func (p repoPattern) match(fullName string) bool {
+	if p.exp != nil {
+		return p.exp.MatchString(fullName)
+	}
+
+	ok, _ := path.Match(p.glob, strings.ToLower(fullName))
+
+	return ok
+}
+
+// GetRepoConfig gets the configuration of a repository (full name).
+// The layers are, the first defined value wins:
+// the exact key, the matching patterns (the most specific first),
+// the configuration file of the repository (can be nil), and the default configuration.
+// The configuration file of the repository can only tighten the requirements of the central configuration
+// (minReview, minLightReview, needMilestone, the up-to-date checks, the merge queue, the merge windows and the freezes).
+func (c Configuration) GetRepoConfig(fullName string, repoFile *RepoConfig) RepoConfig {
+	central := c.mergeRepoConfig(fullName, nil)
+
+	if repoFile == nil {
+		return central
+	}
+
+	config := c.mergeRepoConfig(fullName, repoFile)
+
+	if repoFile.MinReview != nil {
+		config.MinReview = Int(max(*repoFile.MinReview, central.GetMinReview()))
+	}
+
+	if repoFile.MinLightReview != nil {
+		config.MinLightReview = Int(strictestLightReview(central.GetMinLightReview(), *repoFile.MinLightReview, config.GetMinReview()))
+	}
+
+	if repoFile.NeedMilestone != nil {
+		config.NeedMilestone = Bool(*repoFile.NeedMilestone || central.GetNeedMilestone())
+	}
+
+	if repoFile.CheckNeedUpToDate != nil {
+		config.CheckNeedUpToDate = Bool(*repoFile.CheckNeedUpToDate || central.GetCheckNeedUpToDate())
+	}
+
+	if repoFile.ForceNeedUpToDate != nil {
+		config.ForceNeedUpToDate = Bool(*repoFile.ForceNeedUpToDate || central.GetForceNeedUpToDate())
+	}
+
+	if repoFile.MergeQueue != nil {
+		config.MergeQueue = Bool(*repoFile.MergeQueue && central.GetMergeQueue())
+	}
+
+	if repoFile.UpdateWhenFrozen != nil {
+		config.UpdateWhenFrozen = Bool(*repoFile.UpdateWhenFrozen && central.GetUpdateWhenFrozen())
+	}
+
+	// the central merge windows and freezes are checked in their own time zone:
+	// the merges are allowed only inside the windows of both configurations, and outside the freezes of both configurations.
+	if len(central.MergeWindows) > 0 || len(central.Freezes) > 0 {
+		config.bound = &RepoConfig{
+			MergeWindows: central.MergeWindows,
+			Freezes:      central.Freezes,
+			TimeZone:     central.TimeZone,
+		}
+	}
+
+	return config
+}
+
+// mergeRepoConfig merges the layers of the configuration of a repository, the first defined value wins.
+func (c Configuration) mergeRepoConfig(fullName string, repoFile *RepoConfig) RepoConfig {
+	config := RepoConfig{}
+
+	if exact, ok := c.Repositories[fullName]; ok && exact != nil {
+		applyDefault(&config, *exact)
+	}
+
+	for _, pattern := range c.getRepoPatterns() {
+		if pattern.match(fullName) {
+			applyDefault(&config, *c.Repositories[pattern.key])
+		}
+	}
+
+	if repoFile != nil {
+		applyDefault(&config, *repoFile)
+	}
+
+	applyDefault(&config, c.GetDefault(fullName))
+
+	return config
+}
+
+// strictestLightReview gets the value of minLightReview that requires the most reviews:
+// 0 disables the light reviews, the pull requests with the light review label need minReview reviews.
+func strictestLightReview(central, file, minReview int) int {
+	required := func(minLightReview int) int {
+		if minLightReview == 0 {
+			return minReview
+		}
+
+		return minLightReview
+	}
+
+	if required(file) >= required(central) {
+		return file
+	}
+
+	return central
+}
+
+// ParseRepoConfig parses the configuration file of a repository.
+// The unknown fields are rejected to report the typos to the maintainers of the repository.
+func ParseRepoConfig(data []byte) (*RepoConfig, error) {
+	config := &RepoConfig{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ValidateRepoConfig validates the configuration of a repository.
+func (c Configuration) ValidateRepoConfig(config RepoConfig) error {
+	var pbs problems
+	pbs.validateRepoConfig(nil, config, c, true)
+
+	if len(pbs) > 0 {
+		return &ValidationError{Problems: pbs}
+	}
+
+	return nil
+}
+
+// getRepoPatterns gets the patterns of the repositories configuration, sorted from the most specific to the least specific:
+// the globs with the most literal characters first, then the regular expressions.
+func (c Configuration) getRepoPatterns() []repoPattern {
+	var patterns []repoPattern
+
+	for key, config := range c.Repositories {
+		if config == nil || !isRepoPattern(key) {
+			continue
+		}
+
+		// the patterns are validated when the configuration is loaded.
+		pattern, err := parseRepoPattern(key)
+		if err != nil {
+			continue
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].weight != patterns[j].weight {
+			return patterns[i].weight > patterns[j].weight
+		}
+
+		return patterns[i].key < patterns[j].key
+	})
+
+	return patterns
+}
+
+// isRepoPattern checks if a key of the repositories configuration is a pattern:
+// a regular expression (/.../) or a glob (*, ?, [...]).
+func isRepoPattern(key string) bool {
+	return isRegexpKey(key) || strings.ContainsAny(key, "*?[")
+}
+
+func isRegexpKey(key string) bool {
+	return len(key) > 2 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/")
+}
+
+func parseRepoPattern(key string) (repoPattern, error) {
+	if isRegexpKey(key) {
+		exp, err := regexp.Compile("(?i)" + key[1:len(key)-1])
+		if err != nil {
+			return repoPattern{}, err
+		}
+
+		// the regular expressions are less specific than the globs.
+		return repoPattern{key: key, exp: exp, weight: -1}, nil
+	}
+
+	glob := strings.ToLower(key)
+
+	if _, err := path.Match(glob, ""); err != nil {
+		return repoPattern{}, err
+	}
+
+	return repoPattern{key: key, glob: glob, weight: countLiterals(glob)}, nil
+}
+
+// countLiterals counts the characters of a glob that are not wildcards.
+func countLiterals(glob string) int {
+	var count int
+	var inClass bool
+
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case inClass:
+			inClass = glob[i] != ']'
+		case glob[i] == '[':
+			inClass = true
+		case glob[i] == '\\':
+			i++
+			count++
+		case glob[i] != '*' && glob[i] != '?':
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,464 @@
+package conf
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfiguration_GetRepoConfig(t *testing.T) {
+	cfg, err := Load(filepath.FromSlash("./fixtures/config_03.yml"), nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc     string
+		fullName string
+		repoFile *RepoConfig
+		expected RepoConfig
+	}{
+		{
+			desc:     "exact key on top of the patterns",
+			fullName: "foo/plugin-demo",
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(1),
+				MinReview:         Int(2),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "the most specific glob first",
+			fullName: "foo/plugin-docs",
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(2),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("description"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "glob before regular expression",
+			fullName: "foo/traefik-docs",
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(0),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("description"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "case insensitive",
+			fullName: "Foo/Plugin-Auth",
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(2),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file under the patterns",
+			fullName: "foo/plugin-auth",
+			repoFile: &RepoConfig{
+				MergeMethod:   String("squash"),
+				CommitMessage: String("description"),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(2),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("description"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file over the default",
+			fullName: "bar/plugin-auth",
+			repoFile: &RepoConfig{
+				MinReview:     Int(3),
+				CommitMessage: String("description"),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(3),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("description"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file raises minReview over the patterns",
+			fullName: "foo/plugin-auth",
+			repoFile: &RepoConfig{
+				MinReview: Int(3),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(3),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file cannot lower minReview",
+			fullName: "bar/plugin-auth",
+			repoFile: &RepoConfig{
+				MinReview: Int(0),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(1),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file cannot disable needMilestone",
+			fullName: "bar/plugin-auth",
+			repoFile: &RepoConfig{
+				NeedMilestone: Bool(false),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(1),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file can enable needMilestone",
+			fullName: "foo/plugin-auth",
+			repoFile: &RepoConfig{
+				NeedMilestone: Bool(true),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(2),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file cannot enable the light reviews",
+			fullName: "foo/plugin-auth",
+			repoFile: &RepoConfig{
+				MinLightReview: Int(1),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(2),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file can disable the light reviews",
+			fullName: "foo/plugin-demo",
+			repoFile: &RepoConfig{
+				MinReview:      Int(3),
+				MinLightReview: Int(0),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("rebase"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(3),
+				NeedMilestone:     Bool(false),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file cannot loosen the up-to-date checks",
+			fullName: "bar/plugin-auth",
+			repoFile: &RepoConfig{
+				CheckNeedUpToDate: Bool(true),
+				ForceNeedUpToDate: Bool(false),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(1),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(true),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+		{
+			desc:     "repository file cannot enable the merge queue or the updates when frozen",
+			fullName: "bar/plugin-auth",
+			repoFile: &RepoConfig{
+				MergeQueue:       Bool(true),
+				UpdateWhenFrozen: Bool(true),
+			},
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(1),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+				UpdateWhenFrozen:  Bool(false),
+			},
+		},
+		{
+			desc:     "no matching key",
+			fullName: "bar/plugin-auth",
+			expected: RepoConfig{
+				MergeMethod:       String("squash"),
+				MinLightReview:    Int(0),
+				MinReview:         Int(1),
+				NeedMilestone:     Bool(true),
+				CheckNeedUpToDate: Bool(false),
+				ForceNeedUpToDate: Bool(true),
+				AddErrorInComment: Bool(false),
+				CommitMessage:     String("empty"),
+				MergeQueue:        Bool(false),
+				MergeQueueSize:    Int(5),
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, cfg.GetRepoConfig(test.fullName, test.repoFile))
+		})
+	}
+}
+
+func TestConfiguration_GetRepoConfig_mergeFrozen(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	cfg := Configuration{
+		Default: RepoConfig{
+			MergeWindows: []MergeWindow{
+				{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Hours: "09:00-18:00"},
+			},
+			Freezes: []Freeze{
+				{From: "2021-12-20", To: "2022-01-02", Reason: "holidays"},
+			},
+			TimeZone: String("Europe/Paris"),
+		},
+	}
+
+	testCases := []struct {
+		desc     string
+		repoFile string
+		now      time.Time
+		expected string
+	}{
+		{
+			desc:     "central freeze without the freezes of the file",
+			repoFile: "freezes: []",
+			now:      time.Date(2021, time.December, 22, 10, 0, 0, 0, paris),
+			expected: "freeze 2021-12-20 to 2022-01-02: holidays",
+		},
+		{
+			desc:     "freezes of the file and central freezes",
+			repoFile: "freezes: [{from: '2021-03-04', reason: release}]",
+			now:      time.Date(2021, time.December, 22, 10, 0, 0, 0, paris),
+			expected: "freeze 2021-12-20 to 2022-01-02: holidays",
+		},
+		{
+			desc:     "freeze of the file",
+			repoFile: "freezes: [{from: '2021-03-04', reason: release}]",
+			now:      time.Date(2021, time.March, 4, 10, 0, 0, 0, paris),
+			expected: "freeze 2021-03-04: release",
+		},
+		{
+			desc:     "central windows without the windows of the file",
+			repoFile: "mergeWindows: []",
+			now:      time.Date(2021, time.March, 7, 11, 0, 0, 0, paris),
+			expected: "outside the merge windows (Sun 11:00 CET)",
+		},
+		{
+			desc:     "central windows in the central time zone",
+			repoFile: "timeZone: America/New_York",
+			now:      time.Date(2021, time.March, 1, 8, 0, 0, 0, paris),
+			expected: "outside the merge windows (Mon 08:00 CET)",
+		},
+		{
+			desc:     "outside the windows of the file",
+			repoFile: "mergeWindows: [{hours: '10:00-12:00'}]",
+			now:      time.Date(2021, time.March, 1, 9, 30, 0, 0, paris),
+			expected: "outside the merge windows (Mon 09:30 CET)",
+		},
+		{
+			desc:     "outside the central windows",
+			repoFile: "mergeWindows: [{days: [sun]}]",
+			now:      time.Date(2021, time.March, 7, 11, 0, 0, 0, paris),
+			expected: "outside the merge windows (Sun 11:00 CET)",
+		},
+		{
+			desc:     "inside both windows",
+			repoFile: "mergeWindows: [{hours: '10:00-12:00'}]",
+			now:      time.Date(2021, time.March, 1, 10, 0, 0, 0, paris),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			repoFile, err := ParseRepoConfig([]byte(test.repoFile))
+			require.NoError(t, err)
+
+			config := cfg.GetRepoConfig("foo/bar", repoFile)
+
+			reason, err := config.MergeFrozen(test.now)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, reason)
+		})
+	}
+}
+
+func TestParseRepoConfig(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		data     string
+		expected *RepoConfig
+		errored  bool
+	}{
+		{
+			desc: "valid",
+			data: "minReview: 2\nmergeMethod: rebase\n",
+			expected: &RepoConfig{
+				MinReview:   Int(2),
+				MergeMethod: String("rebase"),
+			},
+		},
+		{
+			desc:     "empty",
+			data:     "",
+			expected: &RepoConfig{},
+		},
+		{
+			desc:    "unknown field",
+			data:    "minReviews: 2\n",
+			errored: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := ParseRepoConfig([]byte(test.data))
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, config)
+		})
+	}
+}
+
+func Test_countLiterals(t *testing.T) {
+	testCases := []struct {
+		glob     string
+		expected int
+	}{
+		{glob: "foo/*", expected: 4},
+		{glob: "foo/plugin-*", expected: 11},
+		{glob: "foo/?-[a-z]*", expected: 5},
+		{glob: `foo/\*`, expected: 5},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.glob, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, countLiterals(test.glob))
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package conf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern the pattern of a duration (ex: 1m30s).
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// schemaDefs the types defined once in the schema of the configuration file ($defs).
+var schemaDefs = map[reflect.Type]string{
+	reflect.TypeOf(RepoConfig{}): "repoConfig",
+	reflect.TypeOf(Markers{}):    "markers",
+	reflect.TypeOf(Retry{}):      "retry",
+}
+
+// schemaEnums the allowed values of the fields, by type and field (ex: RepoConfig.mergeMethod).
+var schemaEnums = map[string][]string{
+	"Configuration.forge":      forges,
+	"RepoConfig.mergeMethod":   mergeMethods,
+	"RepoConfig.commitMessage": commitMessages,
+	"Notifier.type":            notifierTypes,
+	"Notifier.events":          events,
+	"MergeWindow.days":         weekdays,
+}
+
+// schemaDeprecated the deprecated fields, by type and field (ex: Extra.debug).
+var schemaDeprecated = map[string]bool{
+	"Extra.debug": true,
+}
+
+type schema map[string]interface{}
+
+// Schema generates the JSON Schema of the configuration file.
+// The defaults are the defaults of Load.
+func Schema() ([]byte, error) {
+	def := defaultConfiguration()
+
+	g := &schemaGenerator{
+		defs: map[string]schema{},
+		defaults: map[reflect.Type]reflect.Value{
+			reflect.TypeOf(RepoConfig{}): reflect.ValueOf(def.Default),
+			reflect.TypeOf(Markers{}):    reflect.ValueOf(def.Markers),
+			reflect.TypeOf(Retry{}):      reflect.ValueOf(def.Retry),
+		},
+	}
+
+	root := g.schemaOf(reflect.TypeOf(def), reflect.ValueOf(def), false)
+	root["$schema"] = schemaDraft
+	root["title"] = "Lobicornis configuration"
+	root["$defs"] = g.defs
+
+	return marshalSchema(root)
+}
+
+// RepoSchema generates the JSON Schema of the repository configuration file (.github/lobicornis.yml).
+// The defaults are the defaults of Load.
+func RepoSchema() ([]byte, error) {
+	def := defaultConfiguration()
+
+	g := &schemaGenerator{}
+
+	root := g.schemaOf(reflect.TypeOf(def.Default), reflect.ValueOf(def.Default), false)
+	root["$schema"] = schemaDraft
+	root["title"] = "Lobicornis repository configuration (" + RepoConfigFile + ")"
+
+	return marshalSchema(root)
+}
+
+type schemaGenerator struct {
+	// defs the definitions of the types of schemaDefs, nil to inline the types.
+	defs map[string]schema
+	// defaults the defaults of the types of schemaDefs.
+	defaults map[reflect.Type]reflect.Value
+}
+
+// schemaOf generates the schema of a type.
+// The value is the default value of the field (can be invalid): it's only used if it's not zero, or if force is true.
+func (g *schemaGenerator) schemaOf(typ reflect.Type, value reflect.Value, force bool) schema {
+	if typ == reflect.TypeOf(time.Duration(0)) {
+		s := schema{"type": "string", "pattern": durationPattern}
+		if value.IsValid() && (force || !value.IsZero()) {
+			s["default"] = time.Duration(value.Int()).String()
+		}
+
+		return s
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if value.IsValid() && !value.IsNil() {
+			return g.schemaOf(typ.Elem(), value.Elem(), true)
+		}
+
+		return g.schemaOf(typ.Elem(), reflect.Value{}, false)
+
+	case reflect.Struct:
+		return g.structSchema(typ, value)
+
+	case reflect.Slice:
+		return schema{"type": "array", "items": g.schemaOf(typ.Elem(), reflect.Value{}, false)}
+
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": g.schemaOf(typ.Elem(), reflect.Value{}, false)}
+	}
+
+	s := schema{"type": schemaType(typ.Kind())}
+	if value.IsValid() && (force || !value.IsZero()) {
+		s["default"] = value.Interface()
+	}
+
+	return s
+}
+
+func (g *schemaGenerator) structSchema(typ reflect.Type, value reflect.Value) schema {
+	if name, ok := schemaDefs[typ]; ok && g.defs != nil {
+		if _, exists := g.defs[name]; !exists {
+			g.defs[name] = g.objectSchema(typ, g.defaults[typ])
+		}
+
+		return schema{"$ref": "#/$defs/" + name}
+	}
+
+	return g.objectSchema(typ, value)
+}
+
+func (g *schemaGenerator) objectSchema(typ reflect.Type, value reflect.Value) schema {
+	properties := schema{}
+
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		var fieldValue reflect.Value
+		if value.IsValid() {
+			fieldValue = value.Field(i)
+		}
+
+		property := g.schemaOf(structField.Type, fieldValue, false)
+
+		if enum, ok := schemaEnums[typ.Name()+"."+name]; ok {
+			if items, ok := property["items"].(schema); ok {
+				items["enum"] = enum
+			} else {
+				property["enum"] = enum
+			}
+		}
+
+		if schemaDeprecated[typ.Name()+"."+name] {
+			property["deprecated"] = true
+		}
+
+		properties[name] = property
+	}
+
+	return schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func schemaType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	default:
+		return "string"
+	}
+}
+
+func marshalSchema(s schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,52 @@
+package conf
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden files.")
+
+func TestSchema(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		generate func() ([]byte, error)
+		golden   string
+	}{
+		{
+			desc:     "configuration file",
+			generate: Schema,
+			golden:   "schema.json",
+		},
+		{
+			desc:     "repository configuration file",
+			generate: RepoSchema,
+			golden:   "repo_schema.json",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			data, err := test.generate()
+			require.NoError(t, err)
+
+			golden := filepath.Join("fixtures", test.golden)
+
+			if *updateGolden {
+				err = ioutil.WriteFile(golden, data, 0o644)
+				require.NoError(t, err)
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			require.NoError(t, err)
+
+			assert.Equal(t, string(expected), string(data), "the schema is outdated: go test ./pkg/conf -run TestSchema -update")
+		})
+	}
+}
@@ -0,0 +1,522 @@
+package conf
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The allowed values of the enumerations.
+var (
+	mergeMethods   = []string{MergeMethodSquash, MergeMethodMerge, MergeMethodRebase, MergeMethodFastForward}
+	commitMessages = []string{"github", "empty", "description"}
+	forges         = []string{ForgeGitHub, ForgeGitLab, ForgeGitea}
+	// weekdays the days of the week, in the order of time.Weekday.
+	weekdays      = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	notifierTypes = []string{NotifierSlack, NotifierMattermost, NotifierTeams, NotifierWebhook}
+	events        = []string{EventMerged, EventUpdated, EventEscalated, EventRetryExhausted}
+)
+
+// Problem a problem of the configuration.
+type Problem struct {
+	// Line the line of the problem in the file, 0 if unknown.
+	Line int
+	// Field the path of the field (ex: repositories.foo/bar.mergeMethod), empty if unknown.
+	Field   string
+	Message string
+
+	path []string
+}
+
+func (p Problem) String() string {
+	var prefix string
+	if p.Line > 0 {
+		prefix = fmt.Sprintf("line %d: ", p.Line)
+	}
+
+	if p.Field != "" {
+		prefix += p.Field + ": "
+	}
+
+	return prefix + p.Message
+}
+
+// ValidationError the problems of a configuration.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func newValidationError(problems []Problem, node *yaml.Node) *ValidationError {
+	for i, problem := range problems {
+		if problem.Line == 0 && problem.path != nil {
+			problems[i].Line = lineOf(node, problem.path)
+		}
+	}
+
+	// the problems are sorted by line, the problems without line last.
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line == 0 || problems[j].Line == 0 {
+			return problems[j].Line == 0 && problems[i].Line != 0
+		}
+
+		return problems[i].Line < problems[j].Line
+	})
+
+	return &ValidationError{Problems: problems}
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		lines = append(lines, problem.String())
+	}
+
+	return "invalid configuration:\n  " + strings.Join(lines, "\n  ")
+}
+
+// problems collects the problems of a configuration.
+type problems []Problem
+
+func (p *problems) add(fieldPath []string, format string, args ...interface{}) {
+	*p = append(*p, Problem{
+		Field:   joinFieldPath(fieldPath),
+		Message: fmt.Sprintf(format, args...),
+		path:    fieldPath,
+	})
+}
+
+type requiredField struct {
+	path  []string
+	value string
+}
+
+// validate validates the configuration.
+func validate(cfg Configuration) []Problem {
+	var pbs problems
+
+	if !contains(forges, cfg.Forge) {
+		pbs.add([]string{"forge"}, "must be one of %s (got %q)", strings.Join(forges, ", "), cfg.Forge)
+
+		// the other fields depend on the forge.
+		return pbs
+	}
+
+	required := []requiredField{
+		{path: []string{"git", "email"}, value: cfg.Git.Email},
+		{path: []string{"git", "userName"}, value: cfg.Git.UserName},
+		{path: []string{"markers", "needMerge"}, value: cfg.Markers.NeedMerge},
+		{path: []string{"markers", "mergeInProgress"}, value: cfg.Markers.MergeInProgress},
+		{path: []string{"markers", "lightReview"}, value: cfg.Markers.LightReview},
+		{path: []string{"markers", "mergeMethodPrefix"}, value: cfg.Markers.MergeMethodPrefix},
+		{path: []string{"markers", "needHumanMerge"}, value: cfg.Markers.NeedHumanMerge},
+		{path: []string{"markers", "noMerge"}, value: cfg.Markers.NoMerge},
+		{path: []string{"markers", "mergeQueue"}, value: cfg.Markers.MergeQueue},
+	}
+
+	if len(cfg.Owners) == 0 {
+		required = append(required, requiredField{path: []string{cfg.Forge, "user"}, value: cfg.getForgeUser()})
+	}
+
+	if cfg.Forge == ForgeGitea {
+		required = append(required, requiredField{path: []string{"gitea", "url"}, value: cfg.Gitea.URL})
+	}
+
+	for _, field := range required {
+		if field.value == "" {
+			pbs.add(field.path, "is required")
+		}
+	}
+
+	if cfg.Github.App.ID < 0 {
+		pbs.add([]string{"github", "app", "id"}, "is invalid")
+	}
+
+	if cfg.Github.App.ID > 0 && cfg.Github.App.PrivateKey == "" {
+		pbs.add([]string{"github", "app", "privateKey"}, "is required")
+	}
+
+	if cfg.Extra.Concurrency < 1 || cfg.Extra.Concurrency > MaxConcurrency {
+		pbs.add([]string{"extra", "concurrency"}, "must be between 1 and %d", MaxConcurrency)
+	}
+
+	if cfg.Git.Cache.MaxAge < 0 {
+		pbs.add([]string{"git", "cache", "maxAge"}, "is invalid")
+	}
+
+	if cfg.Git.Cache.MaxSize < 0 {
+		pbs.add([]string{"git", "cache", "maxSize"}, "is invalid")
+	}
+
+	if cfg.Daemon.Interval <= 0 {
+		pbs.add([]string{"daemon", "interval"}, "must be positive")
+	}
+
+	if cfg.Daemon.Jitter < 0 {
+		pbs.add([]string{"daemon", "jitter"}, "is invalid")
+	}
+
+	pbs.validateRetry(cfg.Retry)
+
+	pbs.validatePriority(cfg.Priority)
+
+	pbs.validateTracing(cfg.Tracing)
+
+	pbs.validateNotifiers(cfg.Notifiers)
+
+	pbs.validatePatterns([]string{"filters", "include"}, cfg.Filters.Include)
+	pbs.validatePatterns([]string{"filters", "exclude"}, cfg.Filters.Exclude)
+
+	pbs.validateRepoConfig([]string{"default"}, cfg.Default, cfg, true)
+
+	pbs.validateOwners(cfg)
+
+	pbs.validateRepositories(cfg)
+
+	return pbs
+}
+
+func (p *problems) validateRetry(retry Retry) {
+	if retry.Number < 0 {
+		p.add([]string{"retry", "number"}, "must be positive or zero")
+	}
+
+	if retry.Interval < 0 {
+		p.add([]string{"retry", "interval"}, "is invalid")
+	}
+
+	if retry.Number > 0 && retry.Interval <= 0 {
+		p.add([]string{"retry", "interval"}, "must be positive when retry.number is defined")
+	}
+
+	if retry.Number == 0 && (retry.OnMergeable || retry.OnStatuses) {
+		p.add([]string{"retry", "number"}, "must be positive when retry.onMergeable or retry.onStatuses is enabled")
+	}
+}
+
+func (p *problems) validatePriority(priority Priority) {
+	for i, label := range priority.Labels {
+		labelPath := []string{"priority", "labels", indexSegment(i)}
+
+		if label == "" {
+			p.add(labelPath, "is required")
+		}
+
+		if contains(priority.Labels[:i], label) {
+			p.add(labelPath, "duplicated label %q", label)
+		}
+	}
+}
+
+func (p *problems) validateTracing(tracing Tracing) {
+	if tracing.Endpoint == "" {
+		return
+	}
+
+	u, err := url.Parse(tracing.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.add([]string{"tracing", "endpoint"}, "must be an HTTP(S) URL (got %q)", tracing.Endpoint)
+	}
+
+	if tracing.ServiceName == "" {
+		p.add([]string{"tracing", "serviceName"}, "is required")
+	}
+}
+
+func (p *problems) validateNotifiers(notifiers map[string]Notifier) {
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		notifier := notifiers[name]
+		field := func(fieldName string) []string {
+			return []string{"notifiers", name, fieldName}
+		}
+
+		if !contains(notifierTypes, notifier.Type) {
+			p.add(field("type"), "must be one of %s (got %q)", strings.Join(notifierTypes, ", "), notifier.Type)
+		}
+
+		u, err := url.Parse(notifier.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add(field("url"), "must be an HTTP(S) URL")
+		}
+
+		for i, event := range notifier.Events {
+			if !contains(events, event) {
+				p.add(append(field("events"), indexSegment(i)), "must be one of %s (got %q)", strings.Join(events, ", "), event)
+			}
+		}
+
+		if notifier.Template != "" {
+			if _, err := template.New(name).Funcs(NotifierFuncs).Parse(notifier.Template); err != nil {
+				p.add(field("template"), "invalid template: %v", err)
+			}
+		}
+
+		logins := make([]string, 0, len(notifier.Handles))
+		for login := range notifier.Handles {
+			logins = append(logins, login)
+		}
+
+		sort.Strings(logins)
+
+		for _, login := range logins {
+			if notifier.Handles[login] == "" {
+				p.add(append(field("handles"), login), "is required")
+			}
+		}
+	}
+}
+
+func (p *problems) validatePatterns(fieldPath []string, patterns []string) {
+	for i, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			p.add(append(copyPath(fieldPath), indexSegment(i)), "invalid pattern %q: %v", pattern, err)
+		}
+	}
+}
+
+func (p *problems) validateOwners(cfg Configuration) {
+	names := make(map[string]struct{})
+
+	for i, owner := range cfg.Owners {
+		ownerPath := []string{"owners", indexSegment(i)}
+
+		if owner.Name == "" {
+			p.add(append(copyPath(ownerPath), "name"), "is required")
+		}
+
+		key := strings.ToLower(owner.Name)
+		if _, ok := names[key]; ok && owner.Name != "" {
+			p.add(append(copyPath(ownerPath), "name"), "is duplicated: %s", owner.Name)
+		}
+		names[key] = struct{}{}
+
+		if owner.Default != nil {
+			p.validateRepoConfig(append(copyPath(ownerPath), "default"), *owner.Default, cfg, true)
+		}
+	}
+}
+
+func (p *problems) validateRepositories(cfg Configuration) {
+	keys := make([]string, 0, len(cfg.Repositories))
+	for key := range cfg.Repositories {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		config := cfg.Repositories[key]
+		repoPath := []string{"repositories", key}
+
+		if isRepoPattern(key) {
+			if _, err := parseRepoPattern(key); err != nil {
+				p.add(repoPath, "invalid pattern: %v", err)
+			}
+		}
+
+		if config != nil {
+			p.validateRepoConfig(repoPath, *config, cfg, false)
+		}
+	}
+}
+
+// validateRepoConfig validates a repository configuration.
+// A complete configuration (ex: default) must define all the required fields.
+func (p *problems) validateRepoConfig(fieldPath []string, config RepoConfig, cfg Configuration, complete bool) {
+	field := func(name string) []string {
+		return append(copyPath(fieldPath), name)
+	}
+
+	if config.MergeMethod != nil && !contains(mergeMethods, *config.MergeMethod) {
+		p.add(field("mergeMethod"), "must be one of %s (got %q)", strings.Join(mergeMethods, ", "), *config.MergeMethod)
+	}
+
+	if complete && config.MergeMethod == nil {
+		p.add(field("mergeMethod"), "is required")
+	}
+
+	if config.CommitMessage != nil && !contains(commitMessages, *config.CommitMessage) {
+		p.add(field("commitMessage"), "must be one of %s (got %q)", strings.Join(commitMessages, ", "), *config.CommitMessage)
+	}
+
+	if config.MinReview != nil && *config.MinReview < 0 || complete && config.MinReview == nil {
+		p.add(field("minReview"), "is invalid")
+	}
+
+	if config.MinLightReview != nil && *config.MinLightReview < 0 || complete && config.MinLightReview == nil {
+		p.add(field("minLightReview"), "is invalid")
+	}
+
+	if config.MergeQueueSize != nil && *config.MergeQueueSize < 1 || complete && config.MergeQueueSize == nil {
+		p.add(field("mergeQueueSize"), "must be positive")
+	}
+
+	if cfg.Forge != ForgeGitHub && config.GetMergeQueue() {
+		p.add(field("mergeQueue"), "is not supported by %s", cfg.Forge)
+	}
+
+	for i, name := range config.Notify {
+		if _, ok := cfg.Notifiers[name]; !ok {
+			p.add(append(field("notify"), indexSegment(i)), "unknown notifier %q", name)
+		}
+	}
+
+	if config.TimeZone != nil {
+		if _, err := time.LoadLocation(*config.TimeZone); err != nil {
+			p.add(field("timeZone"), "invalid time zone %q", *config.TimeZone)
+		}
+	}
+
+	for i, window := range config.MergeWindows {
+		windowPath := append(field("mergeWindows"), indexSegment(i))
+
+		for _, day := range window.Days {
+			if !contains(weekdays, day) {
+				p.add(append(copyPath(windowPath), "days"), "must be one of %s (got %q)", strings.Join(weekdays, ", "), day)
+			}
+		}
+
+		if window.Hours != "" {
+			if _, _, err := parseHours(window.Hours); err != nil {
+				p.add(append(copyPath(windowPath), "hours"), "%v", err)
+			}
+		}
+	}
+
+	for i, freeze := range config.Freezes {
+		freezePath := append(field("freezes"), indexSegment(i))
+
+		from, to, err := freeze.days(time.UTC)
+		switch {
+		case err != nil:
+			p.add(freezePath, "invalid day (ex: 2021-12-24): %v", err)
+		case !from.Before(to):
+			p.add(append(copyPath(freezePath), "to"), "must not be before from")
+		}
+	}
+}
+
+var typeErrorExp = regexp.MustCompile(`^line (\d+): (.+)$`)
+
+// decodeProblems converts the errors of the decoder (unknown fields, invalid types) to problems.
+func decodeProblems(err *yaml.TypeError) []Problem {
+	var pbs []Problem
+
+	for _, msg := range err.Errors {
+		submatch := typeErrorExp.FindStringSubmatch(msg)
+		if submatch == nil {
+			pbs = append(pbs, Problem{Message: msg})
+			continue
+		}
+
+		line, _ := strconv.Atoi(submatch[1])
+		pbs = append(pbs, Problem{Line: line, Message: submatch[2]})
+	}
+
+	return pbs
+}
+
+// lineOf finds the line of a field in the YAML document.
+// If the field is not defined, the line of the closest defined parent is returned.
+func lineOf(node *yaml.Node, fieldPath []string) int {
+	if node == nil {
+		return 0
+	}
+
+	current := node
+	if current.Kind == yaml.DocumentNode {
+		if len(current.Content) == 0 {
+			return 0
+		}
+		current = current.Content[0]
+	}
+
+	line := 0
+
+	for _, segment := range fieldPath {
+		next := childNode(current, segment)
+		if next == nil {
+			break
+		}
+
+		line = next.Line
+		current = next
+	}
+
+	return line
+}
+
+// childNode gets the value of a key of a mapping, or an item of a sequence.
+// For a mapping, the node of the key is returned when the value is a collection: its line is the line of the key.
+func childNode(node *yaml.Node, segment string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != segment {
+				continue
+			}
+
+			value := node.Content[i+1]
+			if value.Kind == yaml.ScalarNode {
+				return value
+			}
+
+			// the line of the key, with the content of the value.
+			return &yaml.Node{Kind: value.Kind, Content: value.Content, Line: node.Content[i].Line}
+		}
+
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(strings.Trim(segment, "[]"))
+		if err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index]
+		}
+	}
+
+	return nil
+}
+
+func joinFieldPath(fieldPath []string) string {
+	var b strings.Builder
+
+	for i, segment := range fieldPath {
+		if i > 0 && !strings.HasPrefix(segment, "[") {
+			b.WriteString(".")
+		}
+
+		b.WriteString(segment)
+	}
+
+	return b.String()
+}
+
+func indexSegment(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+func copyPath(fieldPath []string) []string {
+	return append([]string(nil), fieldPath...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package conf
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_invalid(t *testing.T) {
+	_, err := Load(filepath.FromSlash("./fixtures/config_invalid.yml"), nil)
+	require.Error(t, err)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	var problems []string
+	for _, problem := range validationErr.Problems {
+		problems = append(problems, problem.String())
+	}
+
+	expected := []string{
+		"line 4: git.userName: is required",
+		"line 9: retry.interval: must be positive when retry.number is defined",
+		"line 12: default.mergeMethod: must be one of squash, merge, rebase, ff (got \"squosh\")",
+		"line 13: field minReviews not found in type conf.RepoConfig",
+		"line 17: repositories.foo/bar.commitMessage: must be one of github, empty, description (got \"title\")",
+		"line 18: repositories.foo/[: invalid pattern: syntax error in pattern",
+		"line 19: repositories.foo/[.minReview: is invalid",
+		"line 22: tracing.endpoint: must be an HTTP(S) URL (got \"localhost:4318\")",
+		"line 26: notifiers.team.type: must be one of slack, mattermost, teams, webhook (got \"discord\")",
+		"line 27: notifiers.team.url: must be an HTTP(S) URL",
+		"line 28: notifiers.team.events[0]: must be one of merged, updated, escalated, retryExhausted (got \"closed\")",
+		"line 29: notifiers.team.template: invalid template: template: team:1: unclosed action",
+		"line 31: notifiers.team.handles.alice: is required",
+		"line 34: priority.labels[1]: duplicated label \"bot/priority-high\"",
+	}
+
+	assert.Equal(t, expected, problems)
+}
+
+func TestConfiguration_ValidateRepoConfig(t *testing.T) {
+	cfg := Configuration{Forge: ForgeGitLab}
+
+	config := RepoConfig{
+		MergeMethod:    String("ff"),
+		MinLightReview: Int(0),
+		MinReview:      Int(1),
+		CommitMessage:  String("empty"),
+		MergeQueue:     Bool(false),
+		MergeQueueSize: Int(5),
+	}
+
+	require.NoError(t, cfg.ValidateRepoConfig(config))
+
+	config.MergeQueue = Bool(true)
+	config.CommitMessage = String("none")
+	config.Notify = []string{"team"}
+	config.TimeZone = String("Mars/Olympus")
+	config.MergeWindows = []MergeWindow{{Days: []string{"monday"}, Hours: "09:00-09:00"}}
+	config.Freezes = []Freeze{{From: "2021-12-24", To: "2021-12-20"}, {From: "christmas"}}
+
+	err := cfg.ValidateRepoConfig(config)
+	require.Error(t, err)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Problems, 8)
+}
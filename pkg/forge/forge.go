@@ -0,0 +1,91 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+const (
+	// Pending Check state.
+	Pending = "pending"
+	// Success Check state.
+	Success = "success"
+)
+
+// ErrNotFound the requested object doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Criteria the criteria of a pull request search.
+type Criteria struct {
+	Labels         []string
+	ExcludedLabels []string
+	// Repository the full name of a repository (owner/name).
+	Repository     string
+	ReviewApproved bool
+}
+
+// Forge the operations of a code hosting platform used by the bot.
+// The pull requests (GitLab: merge requests) are described with the go-github types.
+type Forge interface {
+	// Search searches the open pull requests of an owner, by repository (full name), the least recently updated first.
+	Search(ctx context.Context, owner string, criteria Criteria) (map[string][]*github.Issue, error)
+
+	// GetRepository gets a repository, with its topics.
+	GetRepository(ctx context.Context, owner, repo string) (*github.Repository, error)
+	// GetFileContent gets the content of a file from the default branch of a repository.
+	// Returns ErrNotFound if the file doesn't exist.
+	GetFileContent(ctx context.Context, owner, repo, path string) ([]byte, error)
+	// GetPullRequest gets a pull request.
+	GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error)
+	// GetIssue gets a pull request as an issue, mainly to get the up-to-date labels.
+	GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error)
+	// ListReviews lists the reviews (GitLab: approvals) of a pull request.
+	ListReviews(ctx context.Context, owner, repo string, number int) ([]*github.PullRequestReview, error)
+	// GetFirstCommit gets the first commit of a pull request.
+	GetFirstCommit(ctx context.Context, owner, repo string, number int) (*github.RepositoryCommit, error)
+	// GetBehindBy gets the number of commits of the base branch missing in a pull request.
+	GetBehindBy(ctx context.Context, owner, repo string, pr *github.PullRequest) (int, error)
+	// GetLabeledAt gets the last time a label was added to a pull request.
+	// Returns ErrNotFound if the label was never added.
+	GetLabeledAt(ctx context.Context, owner, repo string, number int, label string) (time.Time, error)
+
+	// GetCommitState gets the aggregated state of the checks (GitLab: pipelines) of a commit.
+	// Returns Success, Pending, or an error describing the failed checks.
+	GetCommitState(ctx context.Context, owner, repo, ref string) (string, error)
+	// NeedUpToDate checks if the branches must be up-to-date before merging into a branch.
+	NeedUpToDate(ctx context.Context, owner, repo, branch string) (bool, error)
+	// CompareCommits compares two commits of a repository.
+	CompareCommits(ctx context.Context, owner, repo, base, head string) (*github.CommitsComparison, error)
+	// GetCommit gets a commit of a repository.
+	GetCommit(ctx context.Context, owner, repo, sha string) (*github.RepositoryCommit, error)
+
+	// AddLabels adds some labels on a pull request.
+	AddLabels(ctx context.Context, owner, repo string, number int, labels ...string) error
+	// RemoveLabel removes a label from a pull request.
+	RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error
+	// ReplaceLabels replaces all the labels of a pull request.
+	ReplaceLabels(ctx context.Context, owner, repo string, number int, labels []string) error
+	// CreateComment adds a comment on a pull request.
+	CreateComment(ctx context.Context, owner, repo string, number int, body string) error
+
+	// Merge merges a pull request.
+	Merge(ctx context.Context, owner, repo string, number int, commitMessage string, options *github.PullRequestOptions) (*github.PullRequestMergeResult, error)
+	// UpdateBranch updates a pull request with the base branch, without cloning it.
+	UpdateBranch(ctx context.Context, owner, repo string, number int) error
+
+	// CloseIssue closes an issue, and sets its milestone.
+	CloseIssue(ctx context.Context, owner, repo string, number int, milestone *github.Milestone) error
+	// CreateIssueComment adds a comment on an issue.
+	CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) error
+
+	// GetBranch gets the SHA of the head of a branch.
+	// Returns ErrNotFound if the branch doesn't exist.
+	GetBranch(ctx context.Context, owner, repo, branch string) (string, error)
+	// FastForward fast-forwards a branch to a commit.
+	FastForward(ctx context.Context, owner, repo, branch, sha string) error
+	// DeleteBranch deletes a branch.
+	DeleteBranch(ctx context.Context, owner, repo, branch string) error
+}
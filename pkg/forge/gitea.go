@@ -0,0 +1,861 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+)
+
+const giteaPageSize = 50
+
+// Gitea the Gitea (and Forgejo) forge.
+type Gitea struct {
+	client  *http.Client
+	baseURL *url.URL
+	token   string
+}
+
+// NewGitea creates a new Gitea forge.
+// The URL is the root URL of the instance (ex: https://gitea.example.com).
+func NewGitea(client *http.Client, rawURL, token string) (*Gitea, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(rawURL, "/") + "/api/v1")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Gitea URL: %w", err)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Gitea{client: client, baseURL: baseURL, token: token}, nil
+}
+
+// Search searches the open pull requests of an owner.
+// The criteria ReviewApproved is ignored: the reviews are checked for each pull request.
+func (g *Gitea) Search(ctx context.Context, owner string, criteria Criteria) (map[string][]*github.Issue, error) {
+	query := url.Values{}
+	query.Set("type", "pulls")
+	query.Set("state", "open")
+	query.Set("owner", owner)
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+
+	if len(criteria.Labels) > 0 {
+		query.Set("labels", strings.Join(criteria.Labels, ","))
+	}
+
+	var issues []*giteaIssue
+	var count int
+	for page := 1; ; page++ {
+		count++
+
+		query.Set("page", strconv.Itoa(page))
+
+		var result []*giteaIssue
+		err := g.do(ctx, http.MethodGet, "/repos/issues/search", query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, result...)
+
+		if len(result) < giteaPageSize {
+			break
+		}
+	}
+
+	log.Debug().Str("owner", owner).Int("count", count).Msg("search queries count")
+
+	// the API filters the labels with an OR, and doesn't sort by update date.
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].UpdatedAt.Before(issues[j].UpdatedAt)
+	})
+
+	overview := make(map[string][]*github.Issue)
+	for _, issue := range issues {
+		if issue.Repository == nil || !matchCriteria(issue, criteria) {
+			continue
+		}
+
+		fullName := issue.Repository.FullName
+		overview[fullName] = append(overview[fullName], issue.toIssue())
+	}
+
+	return overview, nil
+}
+
+// GetRepository gets a repository, with its topics.
+func (g *Gitea) GetRepository(ctx context.Context, owner, repo string) (*github.Repository, error) {
+	var repository giteaRepository
+	err := g.do(ctx, http.MethodGet, repoPath(owner, repo), nil, nil, &repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var topics giteaTopics
+	err = g.do(ctx, http.MethodGet, repoPath(owner, repo)+"/topics", nil, nil, &topics)
+	if err != nil {
+		return nil, err
+	}
+
+	result := repository.toRepository()
+	result.Topics = topics.Topics
+
+	return result, nil
+}
+
+// GetFileContent gets the content of a file from the default branch of a repository.
+func (g *Gitea) GetFileContent(ctx context.Context, owner, repo, path string) ([]byte, error) {
+	var file giteaContent
+	err := g.do(ctx, http.MethodGet, repoPath(owner, repo)+"/contents/"+path, nil, nil, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Type != "file" {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+
+	return base64.StdEncoding.DecodeString(file.Content)
+}
+
+// GetPullRequest gets a pull request.
+func (g *Gitea) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	pr, err := g.getPullRequest(ctx, owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return pr.toPullRequest(), nil
+}
+
+func (g *Gitea) getPullRequest(ctx context.Context, owner, repo string, number int) (*giteaPullRequest, error) {
+	var pr giteaPullRequest
+	err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), nil, nil, &pr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// GetIssue gets a pull request as an issue.
+func (g *Gitea) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	var issue giteaIssue
+	err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/issues/%d", repoPath(owner, repo), number), nil, nil, &issue)
+	if err != nil {
+		return nil, err
+	}
+
+	return issue.toIssue(), nil
+}
+
+// ListReviews lists the reviews of a pull request.
+func (g *Gitea) ListReviews(ctx context.Context, owner, repo string, number int) ([]*github.PullRequestReview, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+
+	var reviews []*github.PullRequestReview
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var result []*giteaReview
+		err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d/reviews", repoPath(owner, repo), number), query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, review := range result {
+			state := review.toState()
+			if state == "" || review.User == nil {
+				continue
+			}
+
+			reviews = append(reviews, &github.PullRequestReview{
+				User:  &github.User{Login: github.String(review.User.Login)},
+				State: github.String(state),
+			})
+		}
+
+		if len(result) < giteaPageSize {
+			break
+		}
+	}
+
+	return reviews, nil
+}
+
+// GetFirstCommit gets the first commit of a pull request.
+func (g *Gitea) GetFirstCommit(ctx context.Context, owner, repo string, number int) (*github.RepositoryCommit, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+
+	var commits []*giteaCommit
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var result []*giteaCommit
+		err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d/commits", repoPath(owner, repo), number), query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		commits = append(commits, result...)
+
+		if len(result) < giteaPageSize {
+			break
+		}
+	}
+
+	first := findFirstCommit(commits)
+	if first == nil {
+		return nil, errors.New("no commit")
+	}
+
+	return &github.RepositoryCommit{
+		SHA:    github.String(first.SHA),
+		Commit: &github.Commit{SHA: github.String(first.SHA), Message: github.String(first.Commit.Message)},
+	}, nil
+}
+
+// GetBehindBy checks if the base branch has changed since the merge base of a pull request.
+// Gitea doesn't count the commits: the result is 1 when the base branch has changed.
+func (g *Gitea) GetBehindBy(ctx context.Context, owner, repo string, pr *github.PullRequest) (int, error) {
+	giteaPR, err := g.getPullRequest(ctx, owner, repo, pr.GetNumber())
+	if err != nil {
+		return 0, err
+	}
+
+	sha, err := g.GetBranch(ctx, owner, repo, pr.Base.GetRef())
+	if err != nil {
+		return 0, err
+	}
+
+	log.Ctx(ctx).Debug().Str("sha", giteaPR.MergeBase).Msgf("Merge base commit, base branch %s", sha)
+
+	if giteaPR.MergeBase == sha {
+		return 0, nil
+	}
+
+	return 1, nil
+}
+
+// GetLabeledAt gets the last time a label was added to a pull request (timeline of the issue).
+func (g *Gitea) GetLabeledAt(ctx context.Context, owner, repo string, number int, label string) (time.Time, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+
+	var labeledAt time.Time
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var events []*giteaTimelineEvent
+		err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/issues/%d/timeline", repoPath(owner, repo), number), query, nil, &events)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		for _, event := range events {
+			// the body of a label event is "1" when the label is added.
+			if event.Type == "label" && event.Body == "1" && event.Label != nil && event.Label.Name == label && event.CreatedAt.After(labeledAt) {
+				labeledAt = event.CreatedAt
+			}
+		}
+
+		if len(events) < giteaPageSize {
+			break
+		}
+	}
+
+	if labeledAt.IsZero() {
+		return time.Time{}, ErrNotFound
+	}
+
+	return labeledAt, nil
+}
+
+// GetCommitState gets the combined status of a commit.
+func (g *Gitea) GetCommitState(ctx context.Context, owner, repo, ref string) (string, error) {
+	var sts giteaCombinedStatus
+	err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/commits/%s/status", repoPath(owner, repo), url.PathEscape(ref)), nil, nil, &sts)
+	if err != nil {
+		return "", err
+	}
+
+	return sts.toState()
+}
+
+// NeedUpToDate checks if the branches must be up-to-date (branch protection).
+func (g *Gitea) NeedUpToDate(ctx context.Context, owner, repo, branch string) (bool, error) {
+	var protection giteaBranchProtection
+	err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/branch_protections/%s", repoPath(owner, repo), url.PathEscape(branch)), nil, nil, &protection)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return protection.BlockOnOutdatedBranch, nil
+}
+
+// CompareCommits is not supported by Gitea.
+func (g *Gitea) CompareCommits(_ context.Context, _, _, base, head string) (*github.CommitsComparison, error) {
+	return nil, fmt.Errorf("the comparison of %s and %s is not supported by Gitea", base, head)
+}
+
+// GetCommit is not supported by Gitea.
+func (g *Gitea) GetCommit(_ context.Context, _, _, sha string) (*github.RepositoryCommit, error) {
+	return nil, fmt.Errorf("getting the commit %s is not supported by Gitea", sha)
+}
+
+// AddLabels adds some labels on a pull request.
+func (g *Gitea) AddLabels(ctx context.Context, owner, repo string, number int, labels ...string) error {
+	ids, err := g.getLabelIDs(ctx, owner, repo, labels)
+	if err != nil {
+		return err
+	}
+
+	body := map[string][]int64{"labels": ids}
+
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("%s/issues/%d/labels", repoPath(owner, repo), number), nil, body, nil)
+}
+
+// RemoveLabel removes a label from a pull request.
+func (g *Gitea) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	ids, err := g.getLabelIDs(ctx, owner, repo, []string{label})
+	if err != nil {
+		return err
+	}
+
+	return g.do(ctx, http.MethodDelete, fmt.Sprintf("%s/issues/%d/labels/%d", repoPath(owner, repo), number, ids[0]), nil, nil, nil)
+}
+
+// ReplaceLabels replaces all the labels of a pull request.
+func (g *Gitea) ReplaceLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	ids, err := g.getLabelIDs(ctx, owner, repo, labels)
+	if err != nil {
+		return err
+	}
+
+	if ids == nil {
+		ids = []int64{}
+	}
+
+	body := map[string][]int64{"labels": ids}
+
+	return g.do(ctx, http.MethodPut, fmt.Sprintf("%s/issues/%d/labels", repoPath(owner, repo), number), nil, body, nil)
+}
+
+// getLabelIDs gets the IDs of some labels of a repository, or of its organization.
+func (g *Gitea) getLabelIDs(ctx context.Context, owner, repo string, names []string) ([]int64, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	labels, err := g.listLabels(ctx, repoPath(owner, repo)+"/labels")
+	if err != nil {
+		return nil, err
+	}
+
+	orgLabels, err := g.listLabels(ctx, "/orgs/"+url.PathEscape(owner)+"/labels")
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	labels = append(labels, orgLabels...)
+
+	var ids []int64
+	for _, name := range names {
+		id, ok := findLabelID(labels, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown label: %s", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (g *Gitea) listLabels(ctx context.Context, path string) ([]*giteaLabel, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+
+	var labels []*giteaLabel
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var result []*giteaLabel
+		err := g.do(ctx, http.MethodGet, path, query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		labels = append(labels, result...)
+
+		if len(result) < giteaPageSize {
+			return labels, nil
+		}
+	}
+}
+
+// CreateComment adds a comment on a pull request.
+func (g *Gitea) CreateComment(ctx context.Context, owner, repo string, number int, body string) error {
+	return g.CreateIssueComment(ctx, owner, repo, number, body)
+}
+
+// Merge merges a pull request.
+func (g *Gitea) Merge(ctx context.Context, owner, repo string, number int, commitMessage string, options *github.PullRequestOptions) (*github.PullRequestMergeResult, error) {
+	body := giteaMergeOptions{
+		Do:           options.MergeMethod,
+		MergeTitle:   options.CommitTitle,
+		MergeMessage: commitMessage,
+	}
+
+	err := g.do(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/merge", repoPath(owner, repo), number), nil, body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &github.PullRequestMergeResult{
+		Merged:  github.Bool(true),
+		Message: github.String("Pull Request successfully merged"),
+	}, nil
+}
+
+// UpdateBranch updates a pull request with the base branch (merge).
+func (g *Gitea) UpdateBranch(ctx context.Context, owner, repo string, number int) error {
+	query := url.Values{}
+	query.Set("style", "merge")
+
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/update", repoPath(owner, repo), number), query, nil, nil)
+}
+
+// CloseIssue closes an issue, and sets its milestone.
+func (g *Gitea) CloseIssue(ctx context.Context, owner, repo string, number int, milestone *github.Milestone) error {
+	body := map[string]interface{}{"state": "closed"}
+
+	if milestone != nil {
+		body["milestone"] = milestone.GetNumber()
+	}
+
+	return g.do(ctx, http.MethodPatch, fmt.Sprintf("%s/issues/%d", repoPath(owner, repo), number), nil, body, nil)
+}
+
+// CreateIssueComment adds a comment on an issue.
+func (g *Gitea) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) error {
+	comment := map[string]string{"body": body}
+
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("%s/issues/%d/comments", repoPath(owner, repo), number), nil, comment, nil)
+}
+
+// GetBranch gets the SHA of the head of a branch.
+func (g *Gitea) GetBranch(ctx context.Context, owner, repo, branch string) (string, error) {
+	var b giteaBranch
+	err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/branches/%s", repoPath(owner, repo), url.PathEscape(branch)), nil, nil, &b)
+	if err != nil {
+		return "", err
+	}
+
+	return b.Commit.ID, nil
+}
+
+// FastForward is not supported by the Gitea API.
+func (g *Gitea) FastForward(_ context.Context, _, _, branch, _ string) error {
+	return fmt.Errorf("the fast-forward of the branch %s is not supported by Gitea", branch)
+}
+
+// DeleteBranch deletes a branch.
+func (g *Gitea) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	return g.do(ctx, http.MethodDelete, fmt.Sprintf("%s/branches/%s", repoPath(owner, repo), url.PathEscape(branch)), nil, nil, nil)
+}
+
+// do sends a request to the Gitea API, and decodes the response into result (if not nil).
+func (g *Gitea) do(ctx context.Context, method, path string, query url.Values, body, result interface{}) error {
+	endpoint := *g.baseURL
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if g.token != "" {
+		req.Header.Set("Authorization", "token "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, path, ErrNotFound)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: status code %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+type giteaLabel struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type giteaTimelineEvent struct {
+	Type      string      `json:"type"`
+	Body      string      `json:"body"`
+	Label     *giteaLabel `json:"label"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type giteaMilestone struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type giteaRepository struct {
+	Name     string     `json:"name"`
+	FullName string     `json:"full_name"`
+	Owner    *giteaUser `json:"owner"`
+	Private  bool       `json:"private"`
+	CloneURL string     `json:"clone_url"`
+	SSHURL   string     `json:"ssh_url"`
+	HTMLURL  string     `json:"html_url"`
+	Archived bool       `json:"archived"`
+}
+
+type giteaTopics struct {
+	Topics []string `json:"topics"`
+}
+
+type giteaContent struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+type giteaIssue struct {
+	Number     int               `json:"number"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	State      string            `json:"state"`
+	HTMLURL    string            `json:"html_url"`
+	User       *giteaUser        `json:"user"`
+	Labels     []*giteaLabel     `json:"labels"`
+	Milestone  *giteaMilestone   `json:"milestone"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Repository *giteaRepository  `json:"repository"`
+	Pull       *giteaPullRequest `json:"pull_request"`
+}
+
+type giteaBranchInfo struct {
+	Label string           `json:"label"`
+	Ref   string           `json:"ref"`
+	SHA   string           `json:"sha"`
+	Repo  *giteaRepository `json:"repo"`
+}
+
+type giteaPullRequest struct {
+	Number              int             `json:"number"`
+	Title               string          `json:"title"`
+	Body                string          `json:"body"`
+	State               string          `json:"state"`
+	HTMLURL             string          `json:"html_url"`
+	User                *giteaUser      `json:"user"`
+	Labels              []*giteaLabel   `json:"labels"`
+	Milestone           *giteaMilestone `json:"milestone"`
+	Mergeable           bool            `json:"mergeable"`
+	Merged              bool            `json:"merged"`
+	AllowMaintainerEdit bool            `json:"allow_maintainer_edit"`
+	MergeBase           string          `json:"merge_base"`
+	Base                giteaBranchInfo `json:"base"`
+	Head                giteaBranchInfo `json:"head"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+type giteaReview struct {
+	User      *giteaUser `json:"user"`
+	State     string     `json:"state"`
+	Dismissed bool       `json:"dismissed"`
+}
+
+type giteaCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+	} `json:"commit"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+}
+
+type giteaStatus struct {
+	Status      string `json:"status"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+}
+
+type giteaCombinedStatus struct {
+	State      string         `json:"state"`
+	TotalCount int            `json:"total_count"`
+	Statuses   []*giteaStatus `json:"statuses"`
+}
+
+type giteaBranchProtection struct {
+	BlockOnOutdatedBranch bool `json:"block_on_outdated_branch"`
+}
+
+type giteaBranch struct {
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+type giteaMergeOptions struct {
+	Do           string `json:"Do"`
+	MergeTitle   string `json:"MergeTitleField,omitempty"`
+	MergeMessage string `json:"MergeMessageField,omitempty"`
+}
+
+func (i *giteaIssue) toIssue() *github.Issue {
+	issue := &github.Issue{
+		Number:    github.Int(i.Number),
+		Title:     github.String(i.Title),
+		Body:      github.String(i.Body),
+		State:     github.String(i.State),
+		HTMLURL:   github.String(i.HTMLURL),
+		User:      i.User.toUser(),
+		Labels:    toGiteaLabels(i.Labels),
+		Milestone: i.Milestone.toMilestone(),
+		CreatedAt: &i.CreatedAt,
+		UpdatedAt: &i.UpdatedAt,
+	}
+
+	if i.Pull != nil {
+		issue.PullRequestLinks = &github.PullRequestLinks{HTMLURL: github.String(i.HTMLURL)}
+	}
+
+	return issue
+}
+
+func (p *giteaPullRequest) toPullRequest() *github.PullRequest {
+	return &github.PullRequest{
+		Number:              github.Int(p.Number),
+		Title:               github.String(p.Title),
+		Body:                github.String(p.Body),
+		State:               github.String(p.State),
+		HTMLURL:             github.String(p.HTMLURL),
+		User:                p.User.toUser(),
+		Labels:              toGiteaLabels(p.Labels),
+		Milestone:           p.Milestone.toMilestone(),
+		Mergeable:           github.Bool(p.Mergeable),
+		Merged:              github.Bool(p.Merged),
+		MaintainerCanModify: github.Bool(p.AllowMaintainerEdit),
+		CreatedAt:           &p.CreatedAt,
+		UpdatedAt:           &p.UpdatedAt,
+		Base:                p.Base.toBranch(),
+		Head:                p.Head.toBranch(),
+	}
+}
+
+func (b giteaBranchInfo) toBranch() *github.PullRequestBranch {
+	branch := &github.PullRequestBranch{
+		Label: github.String(b.Label),
+		Ref:   github.String(b.Ref),
+		SHA:   github.String(b.SHA),
+	}
+
+	if b.Repo != nil {
+		branch.Repo = b.Repo.toRepository()
+		branch.User = b.Repo.Owner.toUser()
+	}
+
+	return branch
+}
+
+func (r *giteaRepository) toRepository() *github.Repository {
+	return &github.Repository{
+		Name:     github.String(r.Name),
+		FullName: github.String(r.FullName),
+		Owner:    r.Owner.toUser(),
+		Private:  github.Bool(r.Private),
+		Archived: github.Bool(r.Archived),
+		GitURL:   github.String(r.CloneURL),
+		SSHURL:   github.String(r.SSHURL),
+		HTMLURL:  github.String(r.HTMLURL),
+	}
+}
+
+func (u *giteaUser) toUser() *github.User {
+	if u == nil {
+		return nil
+	}
+
+	return &github.User{Login: github.String(u.Login)}
+}
+
+func (m *giteaMilestone) toMilestone() *github.Milestone {
+	if m == nil {
+		return nil
+	}
+
+	return &github.Milestone{Number: github.Int(int(m.ID)), Title: github.String(m.Title)}
+}
+
+// toState converts the state of a review to the state of a GitHub review.
+// The pending and dismissed reviews are ignored.
+func (r *giteaReview) toState() string {
+	if r.Dismissed {
+		return ""
+	}
+
+	switch r.State {
+	case "APPROVED":
+		return "APPROVED"
+	case "REQUEST_CHANGES":
+		return "CHANGES_REQUESTED"
+	case "COMMENT":
+		return "COMMENTED"
+	default:
+		return ""
+	}
+}
+
+func (s *giteaCombinedStatus) toState() (string, error) {
+	if s.TotalCount == 0 {
+		return Success, nil
+	}
+
+	switch s.State {
+	case Success, "warning":
+		return Success, nil
+	case Pending:
+		return Pending, nil
+	}
+
+	var summary string
+	for _, stat := range s.Statuses {
+		if stat.Status != Success && stat.Status != "warning" {
+			summary += stat.Context + ": " + stat.Description + "\n"
+		}
+	}
+
+	return "", errors.New(summary)
+}
+
+func toGiteaLabels(giteaLabels []*giteaLabel) []*github.Label {
+	var labels []*github.Label
+	for _, lbl := range giteaLabels {
+		labels = append(labels, &github.Label{Name: github.String(lbl.Name)})
+	}
+
+	return labels
+}
+
+// matchCriteria checks the labels and the repository of an issue.
+func matchCriteria(issue *giteaIssue, criteria Criteria) bool {
+	if criteria.Repository != "" && !strings.EqualFold(issue.Repository.FullName, criteria.Repository) {
+		return false
+	}
+
+	for _, lbl := range criteria.Labels {
+		if _, ok := findLabelID(issue.Labels, lbl); !ok {
+			return false
+		}
+	}
+
+	for _, lbl := range criteria.ExcludedLabels {
+		if _, ok := findLabelID(issue.Labels, lbl); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func findLabelID(labels []*giteaLabel, name string) (int64, bool) {
+	for _, lbl := range labels {
+		if lbl.Name == name {
+			return lbl.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// findFirstCommit finds the commit without parent in the commits of a pull request.
+func findFirstCommit(commits []*giteaCommit) *giteaCommit {
+	shas := make(map[string]struct{})
+	for _, commit := range commits {
+		shas[commit.SHA] = struct{}{}
+	}
+
+	for _, commit := range commits {
+		var inside bool
+		for _, parent := range commit.Parents {
+			if _, ok := shas[parent.SHA]; ok {
+				inside = true
+				break
+			}
+		}
+
+		if !inside {
+			return commit
+		}
+	}
+
+	return nil
+}
+
+func repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
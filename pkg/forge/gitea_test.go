@@ -0,0 +1,249 @@
+package forge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitea_Search(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/issues/search", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "token secret", req.Header.Get("Authorization"))
+		assert.Equal(t, "foo", req.URL.Query().Get("owner"))
+		assert.Equal(t, "pulls", req.URL.Query().Get("type"))
+		assert.Equal(t, "status/3-needs-merge", req.URL.Query().Get("labels"))
+
+		_, _ = rw.Write([]byte(`[
+  {"number": 1, "updated_at": "2021-01-03T00:00:00Z", "repository": {"full_name": "foo/bar"}, "pull_request": {}, "labels": [{"id": 1, "name": "status/3-needs-merge"}]},
+  {"number": 2, "updated_at": "2021-01-01T00:00:00Z", "repository": {"full_name": "foo/bar"}, "pull_request": {}, "labels": [{"id": 1, "name": "status/3-needs-merge"}]},
+  {"number": 3, "updated_at": "2021-01-02T00:00:00Z", "repository": {"full_name": "foo/bar"}, "pull_request": {}, "labels": [{"id": 1, "name": "status/3-needs-merge"}, {"id": 2, "name": "bot/no-merge"}]},
+  {"number": 4, "updated_at": "2021-01-02T00:00:00Z", "repository": {"full_name": "foo/baz"}, "pull_request": {}, "labels": [{"id": 1, "name": "status/3-needs-merge"}]}
+]`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	frg, err := NewGitea(server.Client(), server.URL, "secret")
+	require.NoError(t, err)
+
+	criteria := Criteria{
+		Labels:         []string{"status/3-needs-merge"},
+		ExcludedLabels: []string{"bot/no-merge"},
+		Repository:     "foo/bar",
+	}
+
+	results, err := frg.Search(context.Background(), "foo", criteria)
+	require.NoError(t, err)
+
+	require.Len(t, results, 1)
+	require.Len(t, results["foo/bar"], 2)
+
+	// the least recently updated first.
+	assert.Equal(t, 2, results["foo/bar"][0].GetNumber())
+	assert.Equal(t, 1, results["foo/bar"][1].GetNumber())
+	assert.True(t, results["foo/bar"][0].IsPullRequest())
+}
+
+func TestGitea_GetCommitState(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		body     string
+		expected string
+		errored  bool
+	}{
+		{
+			desc:     "no status",
+			body:     `{"state": "pending", "total_count": 0}`,
+			expected: Success,
+		},
+		{
+			desc:     "success",
+			body:     `{"state": "success", "total_count": 1}`,
+			expected: Success,
+		},
+		{
+			desc:     "warning",
+			body:     `{"state": "warning", "total_count": 1}`,
+			expected: Success,
+		},
+		{
+			desc:     "pending",
+			body:     `{"state": "pending", "total_count": 2}`,
+			expected: Pending,
+		},
+		{
+			desc:    "failure",
+			body:    `{"state": "failure", "total_count": 1, "statuses": [{"status": "failure", "context": "ci", "description": "tests failed"}]}`,
+			errored: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v1/repos/foo/bar/commits/abc/status", func(rw http.ResponseWriter, _ *http.Request) {
+				_, _ = rw.Write([]byte(test.body))
+			})
+
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			frg, err := NewGitea(server.Client(), server.URL, "")
+			require.NoError(t, err)
+
+			state, err := frg.GetCommitState(context.Background(), "foo", "bar", "abc")
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, state)
+		})
+	}
+}
+
+func TestGitea_ListReviews(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/foo/bar/pulls/1/reviews", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write([]byte(`[
+  {"user": {"login": "a"}, "state": "APPROVED"},
+  {"user": {"login": "b"}, "state": "REQUEST_CHANGES"},
+  {"user": {"login": "c"}, "state": "APPROVED", "dismissed": true},
+  {"user": {"login": "d"}, "state": "PENDING"}
+]`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	frg, err := NewGitea(server.Client(), server.URL, "")
+	require.NoError(t, err)
+
+	reviews, err := frg.ListReviews(context.Background(), "foo", "bar", 1)
+	require.NoError(t, err)
+
+	expected := []*github.PullRequestReview{
+		{User: &github.User{Login: github.String("a")}, State: github.String("APPROVED")},
+		{User: &github.User{Login: github.String("b")}, State: github.String("CHANGES_REQUESTED")},
+	}
+
+	assert.Equal(t, expected, reviews)
+}
+
+func TestGitea_GetRepository(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/foo/bar", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"name": "bar", "full_name": "foo/bar", "owner": {"login": "foo"}, "archived": true}`))
+	})
+	mux.HandleFunc("/api/v1/repos/foo/bar/topics", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"topics": ["go", "lobicornis"]}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	frg, err := NewGitea(server.Client(), server.URL, "secret")
+	require.NoError(t, err)
+
+	repo, err := frg.GetRepository(context.Background(), "foo", "bar")
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo/bar", repo.GetFullName())
+	assert.True(t, repo.GetArchived())
+	assert.Equal(t, []string{"go", "lobicornis"}, repo.Topics)
+}
+
+func TestGitea_GetFileContent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/foo/bar/contents/.github/lobicornis.yml", func(rw http.ResponseWriter, req *http.Request) {
+		// minReview: 2
+		_, _ = rw.Write([]byte(`{"type": "file", "encoding": "base64", "content": "bWluUmV2aWV3OiAy"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	frg, err := NewGitea(server.Client(), server.URL, "secret")
+	require.NoError(t, err)
+
+	content, err := frg.GetFileContent(context.Background(), "foo", "bar", ".github/lobicornis.yml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "minReview: 2", string(content))
+
+	_, err = frg.GetFileContent(context.Background(), "foo", "bar", ".github/missing.yml")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGitea_GetLabeledAt(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/foo/bar/issues/1/timeline", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`[
+  {"type": "label", "body": "1", "label": {"name": "bot/merge"}, "created_at": "2021-03-01T10:00:00Z"},
+  {"type": "label", "body": "", "label": {"name": "bot/merge"}, "created_at": "2021-03-02T10:00:00Z"},
+  {"type": "comment", "body": "LGTM", "created_at": "2021-03-02T11:00:00Z"},
+  {"type": "label", "body": "1", "label": {"name": "bot/merge"}, "created_at": "2021-03-03T10:00:00Z"},
+  {"type": "label", "body": "1", "label": {"name": "bot/light-review"}, "created_at": "2021-03-04T10:00:00Z"}
+]`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	frg, err := NewGitea(server.Client(), server.URL, "secret")
+	require.NoError(t, err)
+
+	labeledAt, err := frg.GetLabeledAt(context.Background(), "foo", "bar", 1, "bot/merge")
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2021, time.March, 3, 10, 0, 0, 0, time.UTC), labeledAt)
+
+	_, err = frg.GetLabeledAt(context.Background(), "foo", "bar", 1, "bot/no-merge")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGitea_GetBranch_notFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	frg, err := NewGitea(server.Client(), server.URL, "")
+	require.NoError(t, err)
+
+	_, err = frg.GetBranch(context.Background(), "foo", "bar", "master")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_findFirstCommit(t *testing.T) {
+	commit := func(sha string, parents ...string) *giteaCommit {
+		c := &giteaCommit{SHA: sha}
+		for _, parent := range parents {
+			c.Parents = append(c.Parents, struct {
+				SHA string `json:"sha"`
+			}{SHA: parent})
+		}
+		return c
+	}
+
+	commits := []*giteaCommit{
+		commit("c3", "c2", "m1"),
+		commit("c2", "c1"),
+		commit("c1", "m0"),
+	}
+
+	first := findFirstCommit(commits)
+	require.NotNil(t, first)
+
+	assert.Equal(t, "c1", first.SHA)
+}
@@ -0,0 +1,475 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
+)
+
+// GitHub the GitHub forge.
+type GitHub struct {
+	client *github.Client
+	// owner the owner (user or organization) of the forge, each owner can have its own token and rate limit.
+	owner string
+}
+
+// NewGitHub creates a new GitHub forge.
+func NewGitHub(client *github.Client, owner string) *GitHub {
+	return &GitHub{client: client, owner: owner}
+}
+
+// Search searches the open pull requests of an owner.
+func (g *GitHub) Search(ctx context.Context, owner string, criteria Criteria) (map[string][]*github.Issue, error) {
+	query := searchQuery(owner, criteria)
+
+	searchOpts := &github.SearchOptions{
+		Sort:        "updated",
+		Order:       "asc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	overview := make(map[string][]*github.Issue)
+	var count int
+	for {
+		count++
+		searchResult, resp, err := g.client.Search.Issues(ctx, query, searchOpts)
+		g.observeRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range searchResult.Issues {
+			fullName := getFullName(issue.GetRepositoryURL())
+			overview[fullName] = append(overview[fullName], issue)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		searchOpts.Page = resp.NextPage
+	}
+
+	log.Debug().Str("query", query).Int("count", count).Msg("search queries count")
+
+	return overview, nil
+}
+
+// GetRepository gets a repository, with its topics.
+func (g *GitHub) GetRepository(ctx context.Context, owner, repo string) (*github.Repository, error) {
+	repository, resp, err := g.client.Repositories.Get(ctx, owner, repo)
+	g.observeRate(resp)
+	return repository, err
+}
+
+// GetFileContent gets the content of a file from the default branch of a repository.
+func (g *GitHub) GetFileContent(ctx context.Context, owner, repo, path string) ([]byte, error) {
+	file, _, resp, err := g.client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	g.observeRate(resp)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	if file == nil {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
+
+// GetPullRequest gets a pull request.
+func (g *GitHub) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	pr, resp, err := g.client.PullRequests.Get(ctx, owner, repo, number)
+	g.observeRate(resp)
+	return pr, err
+}
+
+// GetIssue gets a pull request as an issue.
+func (g *GitHub) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	issue, resp, err := g.client.Issues.Get(ctx, owner, repo, number)
+	g.observeRate(resp)
+	return issue, err
+}
+
+// ListReviews lists the reviews of a pull request.
+func (g *GitHub) ListReviews(ctx context.Context, owner, repo string, number int) ([]*github.PullRequestReview, error) {
+	opt := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	var allReviews []*github.PullRequestReview
+	for {
+		reviews, resp, err := g.client.PullRequests.ListReviews(ctx, owner, repo, number, opt)
+		g.observeRate(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		allReviews = append(allReviews, reviews...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return allReviews, nil
+}
+
+// GetFirstCommit gets the first commit of a pull request.
+func (g *GitHub) GetFirstCommit(ctx context.Context, owner, repo string, number int) (*github.RepositoryCommit, error) {
+	options := &github.ListOptions{
+		PerPage: 1,
+	}
+
+	commits, resp, err := g.client.PullRequests.ListCommits(ctx, owner, repo, number, options)
+	g.observeRate(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(commits) == 0 {
+		return nil, errors.New("no commit")
+	}
+
+	return commits[0], nil
+}
+
+// GetBehindBy gets the number of commits of the base branch missing in a pull request.
+func (g *GitHub) GetBehindBy(ctx context.Context, owner, repo string, pr *github.PullRequest) (int, error) {
+	head := fmt.Sprintf("%s:%s", pr.Head.User.GetLogin(), pr.Head.GetRef())
+
+	cc, resp, err := g.client.Repositories.CompareCommits(ctx, owner, repo, pr.Base.GetRef(), head)
+	g.observeRate(resp)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Ctx(ctx).Debug().Str("sha", cc.MergeBaseCommit.GetSHA()).Msgf("Merge base commit, behind By %d", cc.GetBehindBy())
+
+	return cc.GetBehindBy(), nil
+}
+
+// GetLabeledAt gets the last time a label was added to a pull request.
+func (g *GitHub) GetLabeledAt(ctx context.Context, owner, repo string, number int, label string) (time.Time, error) {
+	opt := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	var labeledAt time.Time
+	for {
+		events, resp, err := g.client.Issues.ListIssueEvents(ctx, owner, repo, number, opt)
+		g.observeRate(resp)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		for _, event := range events {
+			if event.GetEvent() == "labeled" && event.GetLabel().GetName() == label && event.GetCreatedAt().After(labeledAt) {
+				labeledAt = event.GetCreatedAt()
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	if labeledAt.IsZero() {
+		return time.Time{}, ErrNotFound
+	}
+
+	return labeledAt, nil
+}
+
+// GetCommitState provide checks status (status + checksSuite) of a commit.
+func (g *GitHub) GetCommitState(ctx context.Context, owner, repo, ref string) (string, error) {
+	status, err := g.getStatus(ctx, owner, repo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if status == Pending {
+		return status, nil
+	}
+
+	return g.getCheckRunsState(ctx, owner, repo, ref)
+}
+
+// getStatus provide checks status (status).
+func (g *GitHub) getStatus(ctx context.Context, owner, repo, ref string) (string, error) {
+	sts, resp, err := g.client.Repositories.GetCombinedStatus(ctx, owner, repo, ref, nil)
+	g.observeRate(resp)
+	if err != nil {
+		return "", err
+	}
+
+	if sts.GetState() == Success {
+		return sts.GetState(), nil
+	}
+
+	// pending: if there are no statuses or a context is pending
+	// https://developer.github.com/v3/repos/statuses/#get-the-combined-status-for-a-specific-ref
+	if sts.GetState() == Pending {
+		if sts.GetTotalCount() == 0 {
+			return Success, nil
+		}
+		return sts.GetState(), nil
+	}
+
+	statuses, resp, err := g.client.Repositories.ListStatuses(ctx, owner, repo, ref, nil)
+	g.observeRate(resp)
+	if err != nil {
+		return "", err
+	}
+
+	var summary string
+	for _, stat := range statuses {
+		if stat.GetState() != Success {
+			summary += stat.GetDescription() + "\n"
+		}
+	}
+
+	return "", errors.New(summary)
+}
+
+// getCheckRunsState provide checks status (checksRun).
+func (g *GitHub) getCheckRunsState(ctx context.Context, owner, repo, ref string) (string, error) {
+	checkSuites, resp, err := g.client.Checks.ListCheckSuitesForRef(ctx, owner, repo, ref, nil)
+	g.observeRate(resp)
+	if err != nil {
+		return "", err
+	}
+
+	return checkSuitesState(checkSuites.CheckSuites)
+}
+
+// checkSuitesState aggregates the states of the check suites of a commit:
+// success only if every check suite (except the skipped ones) is successful or neutral.
+func checkSuitesState(checkSuites []*github.CheckSuite) (string, error) {
+	var failed []string
+	for _, v := range checkSuites {
+		if v.App != nil && strings.EqualFold(v.GetApp().GetName(), "Dependabot") {
+			continue
+		}
+
+		if v.GetStatus() != "completed" {
+			return Pending, nil
+		}
+
+		switch v.GetConclusion() {
+		case "success", "neutral", "skipped":
+		default:
+			failed = append(failed, fmt.Sprintf("%s %s %s", v.GetApp().GetName(), v.GetStatus(), v.GetConclusion()))
+		}
+	}
+
+	if len(failed) > 0 {
+		return "", errors.New(strings.Join(failed, ", "))
+	}
+
+	return Success, nil
+}
+
+// NeedUpToDate checks if the branches must be up-to-date (branch protection).
+func (g *GitHub) NeedUpToDate(ctx context.Context, owner, repo, branch string) (bool, error) {
+	rcs, resp, err := g.client.Repositories.GetRequiredStatusChecks(ctx, owner, repo, branch)
+	g.observeRate(resp)
+	if err != nil {
+		return false, err
+	}
+
+	return rcs.Strict, nil
+}
+
+// CompareCommits compares two commits of a repository.
+func (g *GitHub) CompareCommits(ctx context.Context, owner, repo, base, head string) (*github.CommitsComparison, error) {
+	cc, resp, err := g.client.Repositories.CompareCommits(ctx, owner, repo, base, head)
+	g.observeRate(resp)
+	return cc, err
+}
+
+// GetCommit gets a commit of a repository.
+func (g *GitHub) GetCommit(ctx context.Context, owner, repo, sha string) (*github.RepositoryCommit, error) {
+	commit, resp, err := g.client.Repositories.GetCommit(ctx, owner, repo, sha)
+	g.observeRate(resp)
+	return commit, err
+}
+
+// AddLabels adds some labels on a pull request.
+func (g *GitHub) AddLabels(ctx context.Context, owner, repo string, number int, labels ...string) error {
+	_, resp, err := g.client.Issues.AddLabelsToIssue(ctx, owner, repo, number, labels)
+	g.observeRate(resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to add labels %v. Status code: %d", labels, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// RemoveLabel removes a label from a pull request.
+func (g *GitHub) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	resp, err := g.client.Issues.RemoveLabelForIssue(ctx, owner, repo, number, label)
+	g.observeRate(resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to remove label %s. Status code: %d", label, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ReplaceLabels replaces all the labels of a pull request.
+func (g *GitHub) ReplaceLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	if labels == nil {
+		// Due to go-github/GitHub API constraint
+		labels = []string{}
+	}
+
+	_, resp, err := g.client.Issues.ReplaceLabelsForIssue(ctx, owner, repo, number, labels)
+	g.observeRate(resp)
+	return err
+}
+
+// CreateComment adds a comment on a pull request.
+func (g *GitHub) CreateComment(ctx context.Context, owner, repo string, number int, body string) error {
+	return g.CreateIssueComment(ctx, owner, repo, number, body)
+}
+
+// Merge merges a pull request.
+func (g *GitHub) Merge(ctx context.Context, owner, repo string, number int, commitMessage string, options *github.PullRequestOptions) (*github.PullRequestMergeResult, error) {
+	result, resp, err := g.client.PullRequests.Merge(ctx, owner, repo, number, commitMessage, options)
+	g.observeRate(resp)
+	return result, err
+}
+
+// UpdateBranch updates a pull request with the base branch (update button).
+func (g *GitHub) UpdateBranch(ctx context.Context, owner, repo string, number int) error {
+	_, resp, err := g.client.PullRequests.UpdateBranch(ctx, owner, repo, number, nil)
+	g.observeRate(resp)
+	return err
+}
+
+// CloseIssue closes an issue, and sets its milestone.
+func (g *GitHub) CloseIssue(ctx context.Context, owner, repo string, number int, milestone *github.Milestone) error {
+	issueRequest := &github.IssueRequest{
+		State: github.String("closed"),
+	}
+
+	if milestone != nil {
+		issueRequest.Milestone = milestone.Number
+	}
+
+	_, resp, err := g.client.Issues.Edit(ctx, owner, repo, number, issueRequest)
+	g.observeRate(resp)
+	return err
+}
+
+// CreateIssueComment adds a comment on an issue.
+func (g *GitHub) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) error {
+	comment := &github.IssueComment{Body: github.String(body)}
+
+	_, resp, err := g.client.Issues.CreateComment(ctx, owner, repo, number, comment)
+	g.observeRate(resp)
+	return err
+}
+
+// GetBranch gets the SHA of the head of a branch.
+func (g *GitHub) GetBranch(ctx context.Context, owner, repo, branch string) (string, error) {
+	ref, resp, err := g.client.Git.GetRef(ctx, owner, repo, "heads/"+branch)
+	g.observeRate(resp)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", ErrNotFound
+		}
+
+		return "", err
+	}
+
+	return ref.GetObject().GetSHA(), nil
+}
+
+// FastForward fast-forwards a branch to a commit.
+func (g *GitHub) FastForward(ctx context.Context, owner, repo, branch, sha string) error {
+	reference := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: github.String(sha)},
+	}
+
+	_, resp, err := g.client.Git.UpdateRef(ctx, owner, repo, reference, false)
+	g.observeRate(resp)
+	return err
+}
+
+// DeleteBranch deletes a branch.
+func (g *GitHub) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	resp, err := g.client.Git.DeleteRef(ctx, owner, repo, "heads/"+branch)
+	g.observeRate(resp)
+	return err
+}
+
+func searchQuery(owner string, criteria Criteria) string {
+	query := fmt.Sprintf("user:%s type:pr state:open ", owner)
+
+	for _, lbl := range criteria.Labels {
+		query += fmt.Sprintf("label:%s ", lbl)
+	}
+
+	for _, lbl := range criteria.ExcludedLabels {
+		query += fmt.Sprintf("-label:%s ", lbl)
+	}
+
+	if criteria.Repository != "" {
+		query += fmt.Sprintf("repo:%s ", criteria.Repository)
+	}
+
+	if criteria.ReviewApproved {
+		query += "review:approved "
+	}
+
+	return query
+}
+
+func getFullName(repoURL string) string {
+	n := strings.Split(repoURL, "/")
+	return n[len(n)-2] + "/" + n[len(n)-1]
+}
+
+// observeRate records the remaining requests of the GitHub rate limit.
+func (g *GitHub) observeRate(resp *github.Response) {
+	if resp == nil || resp.Response == nil || resp.Rate.Limit == 0 {
+		return
+	}
+
+	resource := "core"
+	if resp.Request != nil && strings.Contains(resp.Request.URL.Path, "/search/") {
+		resource = "search"
+	}
+
+	metrics.RateLimitRemaining.WithLabelValues(g.owner, resource).Set(float64(resp.Rate.Remaining))
+}
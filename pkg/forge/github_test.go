@@ -0,0 +1,118 @@
+package forge
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_searchQuery(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		criteria Criteria
+		expected string
+	}{
+		{
+			desc:     "no criteria",
+			expected: "user:ldez type:pr state:open ",
+		},
+		{
+			desc: "all criteria",
+			criteria: Criteria{
+				Labels:         []string{"status/3-needs-merge"},
+				ExcludedLabels: []string{"bot/no-merge", "bot/need-human-merge"},
+				Repository:     "traefik/traefik",
+				ReviewApproved: true,
+			},
+			expected: "user:ldez type:pr state:open label:status/3-needs-merge -label:bot/no-merge -label:bot/need-human-merge repo:traefik/traefik review:approved ",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, searchQuery("ldez", test.criteria))
+		})
+	}
+}
+
+func Test_getFullName(t *testing.T) {
+	assert.Equal(t, "traefik/traefik", getFullName("https://api.github.com/repos/traefik/traefik"))
+}
+
+func Test_checkSuitesState(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		checkSuites   []*github.CheckSuite
+		expected      string
+		expectedError string
+	}{
+		{
+			desc:     "no check suite",
+			expected: Success,
+		},
+		{
+			desc: "success",
+			checkSuites: []*github.CheckSuite{
+				newCheckSuite("GitHub Actions", "completed", "success"),
+				newCheckSuite("Semaphore", "completed", "neutral"),
+				newCheckSuite("Netlify", "completed", "skipped"),
+			},
+			expected: Success,
+		},
+		{
+			desc: "pending",
+			checkSuites: []*github.CheckSuite{
+				newCheckSuite("GitHub Actions", "completed", "success"),
+				newCheckSuite("Semaphore", "in_progress", ""),
+			},
+			expected: Pending,
+		},
+		{
+			desc: "one failed check suite",
+			checkSuites: []*github.CheckSuite{
+				newCheckSuite("GitHub Actions", "completed", "success"),
+				newCheckSuite("Semaphore", "completed", "failure"),
+				newCheckSuite("Netlify", "completed", "timed_out"),
+			},
+			expectedError: "Semaphore completed failure, Netlify completed timed_out",
+		},
+		{
+			desc: "Dependabot is ignored",
+			checkSuites: []*github.CheckSuite{
+				newCheckSuite("GitHub Actions", "completed", "success"),
+				newCheckSuite("Dependabot", "completed", "failure"),
+			},
+			expected: Success,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			state, err := checkSuitesState(test.checkSuites)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, state)
+		})
+	}
+}
+
+func newCheckSuite(app, status, conclusion string) *github.CheckSuite {
+	return &github.CheckSuite{
+		App:        &github.App{Name: github.String(app)},
+		Status:     github.String(status),
+		Conclusion: github.String(conclusion),
+	}
+}
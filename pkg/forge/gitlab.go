@@ -0,0 +1,612 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+	"github.com/xanzy/go-gitlab"
+)
+
+// GitLab the GitLab forge.
+// The owner is a group (or a subgroup), and the merge requests are identified by their IID.
+type GitLab struct {
+	client *gitlab.Client
+}
+
+// NewGitLab creates a new GitLab forge.
+func NewGitLab(client *gitlab.Client) *GitLab {
+	return &GitLab{client: client}
+}
+
+// Search searches the open merge requests of a group.
+// The criteria ReviewApproved is ignored: the approvals are checked for each merge request.
+func (g *GitLab) Search(ctx context.Context, owner string, criteria Criteria) (map[string][]*github.Issue, error) {
+	overview := make(map[string][]*github.Issue)
+
+	page := 1
+	var count int
+	for {
+		count++
+
+		mrs, resp, err := g.listMergeRequests(ctx, owner, criteria, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mr := range mrs {
+			fullName := g.getProjectPath(mr.WebURL)
+			overview[fullName] = append(overview[fullName], toIssue(mr))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	log.Debug().Str("group", owner).Int("count", count).Msg("search queries count")
+
+	return overview, nil
+}
+
+func (g *GitLab) listMergeRequests(ctx context.Context, owner string, criteria Criteria, page int) ([]*gitlab.MergeRequest, *gitlab.Response, error) {
+	listOptions := gitlab.ListOptions{Page: page, PerPage: 100}
+
+	if criteria.Repository != "" {
+		opts := &gitlab.ListProjectMergeRequestsOptions{
+			ListOptions: listOptions,
+			State:       gitlab.String("opened"),
+			OrderBy:     gitlab.String("updated_at"),
+			Sort:        gitlab.String("asc"),
+			Labels:      criteria.Labels,
+			NotLabels:   criteria.ExcludedLabels,
+		}
+
+		return g.client.MergeRequests.ListProjectMergeRequests(criteria.Repository, opts, gitlab.WithContext(ctx))
+	}
+
+	opts := &gitlab.ListGroupMergeRequestsOptions{
+		ListOptions: listOptions,
+		State:       gitlab.String("opened"),
+		OrderBy:     gitlab.String("updated_at"),
+		Sort:        gitlab.String("asc"),
+		Labels:      criteria.Labels,
+		NotLabels:   criteria.ExcludedLabels,
+	}
+
+	return g.client.MergeRequests.ListGroupMergeRequests(owner, opts, gitlab.WithContext(ctx))
+}
+
+// GetRepository gets a project, the topics are the tags of the project.
+func (g *GitLab) GetRepository(ctx context.Context, owner, repo string) (*github.Repository, error) {
+	project, _, err := g.client.Projects.GetProject(projectID(owner, repo), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return toRepository(project), nil
+}
+
+// GetFileContent gets the content of a file from the default branch of a project.
+func (g *GitLab) GetFileContent(ctx context.Context, owner, repo, path string) ([]byte, error) {
+	project, _, err := g.client.Projects.GetProject(projectID(owner, repo), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	options := &gitlab.GetRawFileOptions{Ref: gitlab.String(project.DefaultBranch)}
+
+	content, resp, err := g.client.RepositoryFiles.GetRawFile(project.ID, path, options, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// GetPullRequest gets a merge request.
+func (g *GitLab) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	mr, _, err := g.client.MergeRequests.GetMergeRequest(projectID(owner, repo), number, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	target, _, err := g.client.Projects.GetProject(mr.TargetProjectID, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the target project: %w", err)
+	}
+
+	source := target
+	if mr.SourceProjectID != mr.TargetProjectID {
+		source, _, err = g.client.Projects.GetProject(mr.SourceProjectID, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the source project: %w", err)
+		}
+	}
+
+	return toPullRequest(mr, target, source), nil
+}
+
+// GetIssue gets a merge request as an issue.
+func (g *GitLab) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	mr, _, err := g.client.MergeRequests.GetMergeRequest(projectID(owner, repo), number, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return toIssue(mr), nil
+}
+
+// ListReviews lists the approvals of a merge request.
+func (g *GitLab) ListReviews(ctx context.Context, owner, repo string, number int) ([]*github.PullRequestReview, error) {
+	approvals, _, err := g.client.MergeRequestApprovals.GetConfiguration(projectID(owner, repo), number, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews []*github.PullRequestReview
+	for _, approver := range approvals.ApprovedBy {
+		if approver.User == nil {
+			continue
+		}
+
+		reviews = append(reviews, &github.PullRequestReview{
+			User:  &github.User{Login: github.String(approver.User.Username)},
+			State: github.String("APPROVED"),
+		})
+	}
+
+	return reviews, nil
+}
+
+// GetFirstCommit gets the first commit of a merge request.
+func (g *GitLab) GetFirstCommit(ctx context.Context, owner, repo string, number int) (*github.RepositoryCommit, error) {
+	opts := &gitlab.GetMergeRequestCommitsOptions{PerPage: 100}
+
+	var first *gitlab.Commit
+	for {
+		commits, resp, err := g.client.MergeRequests.GetMergeRequestCommits(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		// the most recent commit first.
+		if len(commits) > 0 {
+			first = commits[len(commits)-1]
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	if first == nil {
+		return nil, errors.New("no commit")
+	}
+
+	return toRepositoryCommit(first), nil
+}
+
+// GetBehindBy gets the number of commits of the target branch missing in a merge request.
+func (g *GitLab) GetBehindBy(ctx context.Context, owner, repo string, pr *github.PullRequest) (int, error) {
+	opts := &gitlab.GetMergeRequestsOptions{IncludeDivergedCommitsCount: gitlab.Bool(true)}
+
+	mr, _, err := g.client.MergeRequests.GetMergeRequest(projectID(owner, repo), pr.GetNumber(), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	log.Ctx(ctx).Debug().Msgf("Diverged commits: %d", mr.DivergedCommitsCount)
+
+	return mr.DivergedCommitsCount, nil
+}
+
+// GetLabeledAt gets the last time a label was added to a merge request.
+func (g *GitLab) GetLabeledAt(ctx context.Context, owner, repo string, number int, label string) (time.Time, error) {
+	opts := &gitlab.ListLabelEventsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+
+	var labeledAt time.Time
+	for {
+		events, resp, err := g.client.ResourceLabelEvents.ListMergeRequestsLabelEvents(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		for _, event := range events {
+			if event.Action == "add" && event.Label.Name == label && event.CreatedAt != nil && event.CreatedAt.After(labeledAt) {
+				labeledAt = *event.CreatedAt
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	if labeledAt.IsZero() {
+		return time.Time{}, ErrNotFound
+	}
+
+	return labeledAt, nil
+}
+
+// GetCommitState gets the state of the last pipeline of a commit.
+func (g *GitLab) GetCommitState(ctx context.Context, owner, repo, ref string) (string, error) {
+	opts := &gitlab.ListProjectPipelinesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+		SHA:         gitlab.String(ref),
+		OrderBy:     gitlab.String("id"),
+		Sort:        gitlab.String("desc"),
+	}
+
+	pipelines, _, err := g.client.Pipelines.ListProjectPipelines(projectID(owner, repo), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	if len(pipelines) == 0 {
+		return Success, nil
+	}
+
+	return pipelineState(pipelines[0])
+}
+
+// NeedUpToDate checks if the merge requests must be up-to-date (merge method of the project).
+func (g *GitLab) NeedUpToDate(ctx context.Context, owner, repo, _ string) (bool, error) {
+	project, _, err := g.client.Projects.GetProject(projectID(owner, repo), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	return project.MergeMethod != gitlab.NoFastForwardMerge, nil
+}
+
+// CompareCommits compares two commits of a repository.
+func (g *GitLab) CompareCommits(ctx context.Context, owner, repo, base, head string) (*github.CommitsComparison, error) {
+	ahead, _, err := g.client.Repositories.Compare(projectID(owner, repo), &gitlab.CompareOptions{From: gitlab.String(base), To: gitlab.String(head)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	behind, _, err := g.client.Repositories.Compare(projectID(owner, repo), &gitlab.CompareOptions{From: gitlab.String(head), To: gitlab.String(base)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	cc := &github.CommitsComparison{
+		AheadBy:  github.Int(len(ahead.Commits)),
+		BehindBy: github.Int(len(behind.Commits)),
+	}
+
+	for _, commit := range ahead.Commits {
+		cc.Commits = append(cc.Commits, toRepositoryCommit(commit))
+	}
+
+	return cc, nil
+}
+
+// GetCommit gets a commit of a repository.
+func (g *GitLab) GetCommit(ctx context.Context, owner, repo, sha string) (*github.RepositoryCommit, error) {
+	commit, _, err := g.client.Commits.GetCommit(projectID(owner, repo), sha, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return toRepositoryCommit(commit), nil
+}
+
+// AddLabels adds some labels on a merge request.
+func (g *GitLab) AddLabels(ctx context.Context, owner, repo string, number int, labels ...string) error {
+	opts := &gitlab.UpdateMergeRequestOptions{AddLabels: labels}
+
+	_, _, err := g.client.MergeRequests.UpdateMergeRequest(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	return err
+}
+
+// RemoveLabel removes a label from a merge request.
+func (g *GitLab) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	opts := &gitlab.UpdateMergeRequestOptions{RemoveLabels: gitlab.Labels{label}}
+
+	_, _, err := g.client.MergeRequests.UpdateMergeRequest(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	return err
+}
+
+// ReplaceLabels replaces all the labels of a merge request.
+func (g *GitLab) ReplaceLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	mr, _, err := g.client.MergeRequests.GetMergeRequest(projectID(owner, repo), number, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	// an empty list of labels is ignored by the API: the labels are removed explicitly.
+	opts := &gitlab.UpdateMergeRequestOptions{}
+
+	for _, lbl := range mr.Labels {
+		if !contains(labels, lbl) {
+			opts.RemoveLabels = append(opts.RemoveLabels, lbl)
+		}
+	}
+
+	for _, lbl := range labels {
+		if !contains(mr.Labels, lbl) {
+			opts.AddLabels = append(opts.AddLabels, lbl)
+		}
+	}
+
+	if len(opts.RemoveLabels) == 0 && len(opts.AddLabels) == 0 {
+		return nil
+	}
+
+	_, _, err = g.client.MergeRequests.UpdateMergeRequest(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	return err
+}
+
+// CreateComment adds a comment (note) on a merge request.
+func (g *GitLab) CreateComment(ctx context.Context, owner, repo string, number int, body string) error {
+	opts := &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(body)}
+
+	_, _, err := g.client.Notes.CreateMergeRequestNote(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	return err
+}
+
+// Merge merges (accepts) a merge request.
+// The merge method rebase is not supported: the project merge method is used for the merge method merge.
+func (g *GitLab) Merge(ctx context.Context, owner, repo string, number int, commitMessage string, options *github.PullRequestOptions) (*github.PullRequestMergeResult, error) {
+	opts := &gitlab.AcceptMergeRequestOptions{}
+
+	switch options.MergeMethod {
+	case "squash":
+		opts.Squash = gitlab.Bool(true)
+
+		if commitMessage != "" {
+			opts.SquashCommitMessage = gitlab.String(strings.TrimSpace(options.CommitTitle + "\n\n" + commitMessage))
+		}
+	case "merge":
+		opts.Squash = gitlab.Bool(false)
+	default:
+		return nil, fmt.Errorf("the merge method [%s] is not supported by GitLab", options.MergeMethod)
+	}
+
+	mr, _, err := g.client.MergeRequests.AcceptMergeRequest(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &github.PullRequestMergeResult{
+		SHA:     github.String(mr.MergeCommitSHA),
+		Merged:  github.Bool(mr.State == "merged"),
+		Message: github.String(fmt.Sprintf("Merge request !%d: %s", mr.IID, mr.State)),
+	}, nil
+}
+
+// UpdateBranch rebases a merge request with the API.
+func (g *GitLab) UpdateBranch(ctx context.Context, owner, repo string, number int) error {
+	_, err := g.client.MergeRequests.RebaseMergeRequest(projectID(owner, repo), number, gitlab.WithContext(ctx))
+	return err
+}
+
+// CloseIssue closes an issue, and sets its milestone.
+func (g *GitLab) CloseIssue(ctx context.Context, owner, repo string, number int, milestone *github.Milestone) error {
+	opts := &gitlab.UpdateIssueOptions{StateEvent: gitlab.String("close")}
+
+	if milestone != nil {
+		opts.MilestoneID = milestone.Number
+	}
+
+	_, _, err := g.client.Issues.UpdateIssue(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	return err
+}
+
+// CreateIssueComment adds a comment (note) on an issue.
+func (g *GitLab) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) error {
+	opts := &gitlab.CreateIssueNoteOptions{Body: gitlab.String(body)}
+
+	_, _, err := g.client.Notes.CreateIssueNote(projectID(owner, repo), number, opts, gitlab.WithContext(ctx))
+	return err
+}
+
+// GetBranch gets the SHA of the head of a branch.
+func (g *GitLab) GetBranch(ctx context.Context, owner, repo, branch string) (string, error) {
+	b, resp, err := g.client.Branches.GetBranch(projectID(owner, repo), branch, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", ErrNotFound
+		}
+
+		return "", err
+	}
+
+	return b.Commit.ID, nil
+}
+
+// FastForward is not supported by the GitLab API.
+func (g *GitLab) FastForward(_ context.Context, _, _, branch, _ string) error {
+	return fmt.Errorf("the fast-forward of the branch %s is not supported by GitLab", branch)
+}
+
+// DeleteBranch deletes a branch.
+func (g *GitLab) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	_, err := g.client.Branches.DeleteBranch(projectID(owner, repo), branch, gitlab.WithContext(ctx))
+	return err
+}
+
+// getProjectPath extracts the path of a project from the URL of a merge request.
+// ex: https://gitlab.com/foo/bar/-/merge_requests/1 -> foo/bar
+func (g *GitLab) getProjectPath(webURL string) string {
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return webURL
+	}
+
+	path := strings.TrimPrefix(u.Path, "/")
+
+	// GitLab can be installed under a relative URL.
+	root := strings.TrimPrefix(strings.TrimSuffix(g.client.BaseURL().Path, "api/v4/"), "/")
+	path = strings.TrimPrefix(path, root)
+
+	for _, sep := range []string{"/-/merge_requests/", "/merge_requests/"} {
+		if i := strings.Index(path, sep); i >= 0 {
+			return path[:i]
+		}
+	}
+
+	return path
+}
+
+func pipelineState(pipeline *gitlab.PipelineInfo) (string, error) {
+	switch gitlab.BuildStateValue(pipeline.Status) {
+	case gitlab.Success, gitlab.Skipped, gitlab.Manual:
+		return Success, nil
+	case gitlab.Failed, gitlab.Canceled:
+		return "", fmt.Errorf("pipeline %s: %s", pipeline.Status, pipeline.WebURL)
+	default:
+		return Pending, nil
+	}
+}
+
+func toIssue(mr *gitlab.MergeRequest) *github.Issue {
+	return &github.Issue{
+		Number:    github.Int(mr.IID),
+		Title:     github.String(mr.Title),
+		Body:      github.String(mr.Description),
+		State:     github.String(toState(mr.State)),
+		Labels:    toLabels(mr.Labels),
+		HTMLURL:   github.String(mr.WebURL),
+		CreatedAt: mr.CreatedAt,
+		UpdatedAt: mr.UpdatedAt,
+		PullRequestLinks: &github.PullRequestLinks{
+			HTMLURL: github.String(mr.WebURL),
+		},
+	}
+}
+
+func toPullRequest(mr *gitlab.MergeRequest, target, source *gitlab.Project) *github.PullRequest {
+	pr := &github.PullRequest{
+		Number:              github.Int(mr.IID),
+		Title:               github.String(mr.Title),
+		Body:                github.String(mr.Description),
+		State:               github.String(toState(mr.State)),
+		Merged:              github.Bool(mr.State == "merged"),
+		Mergeable:           github.Bool(mr.MergeStatus == "can_be_merged" && !mr.HasConflicts),
+		MaintainerCanModify: github.Bool(mr.AllowCollaboration),
+		Labels:              toLabels(mr.Labels),
+		HTMLURL:             github.String(mr.WebURL),
+		CreatedAt:           mr.CreatedAt,
+		UpdatedAt:           mr.UpdatedAt,
+		Base: &github.PullRequestBranch{
+			Ref:  github.String(mr.TargetBranch),
+			SHA:  github.String(mr.DiffRefs.BaseSha),
+			Repo: toRepository(target),
+		},
+		Head: &github.PullRequestBranch{
+			Label: github.String(source.PathWithNamespace + ":" + mr.SourceBranch),
+			Ref:   github.String(mr.SourceBranch),
+			SHA:   github.String(mr.SHA),
+			Repo:  toRepository(source),
+		},
+	}
+
+	if source.Namespace != nil {
+		pr.Head.User = &github.User{Login: github.String(source.Namespace.FullPath)}
+	}
+
+	if mr.Author != nil {
+		pr.User = &github.User{Login: github.String(mr.Author.Username)}
+	}
+
+	if mr.Milestone != nil {
+		pr.Milestone = &github.Milestone{
+			Number: github.Int(mr.Milestone.ID),
+			Title:  github.String(mr.Milestone.Title),
+		}
+	}
+
+	return pr
+}
+
+func toRepository(project *gitlab.Project) *github.Repository {
+	repo := &github.Repository{
+		Name:     github.String(project.Path),
+		FullName: github.String(project.PathWithNamespace),
+		Private:  github.Bool(project.Visibility != gitlab.PublicVisibility),
+		GitURL:   github.String(project.HTTPURLToRepo),
+		SSHURL:   github.String(project.SSHURLToRepo),
+		HTMLURL:  github.String(project.WebURL),
+		Archived: github.Bool(project.Archived),
+		Topics:   project.TagList,
+	}
+
+	if project.Namespace != nil {
+		repo.Owner = &github.User{Login: github.String(project.Namespace.FullPath)}
+	}
+
+	return repo
+}
+
+func toRepositoryCommit(commit *gitlab.Commit) *github.RepositoryCommit {
+	rc := &github.RepositoryCommit{
+		SHA: github.String(commit.ID),
+		Commit: &github.Commit{
+			SHA:     github.String(commit.ID),
+			Message: github.String(commit.Message),
+			Committer: &github.CommitAuthor{
+				Name:  github.String(commit.CommitterName),
+				Email: github.String(commit.CommitterEmail),
+			},
+		},
+	}
+
+	for _, id := range commit.ParentIDs {
+		rc.Parents = append(rc.Parents, &github.Commit{SHA: github.String(id)})
+	}
+
+	return rc
+}
+
+func toLabels(names gitlab.Labels) []*github.Label {
+	var labels []*github.Label
+	for _, name := range names {
+		labels = append(labels, &github.Label{Name: github.String(name)})
+	}
+
+	return labels
+}
+
+// toState converts the state of a merge request to the state of a pull request.
+func toState(state string) string {
+	if state == "opened" {
+		return "open"
+	}
+
+	return "closed"
+}
+
+// projectID the ID of a project: its path with namespace.
+func projectID(owner, repo string) string {
+	return owner + "/" + repo
+}
+
+func contains(values []string, value string) bool {
+	for _, val := range values {
+		if value == val {
+			return true
+		}
+	}
+
+	return false
+}
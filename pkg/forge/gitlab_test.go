@@ -0,0 +1,129 @@
+package forge
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestGitLab_getProjectPath(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		baseURL  string
+		webURL   string
+		expected string
+	}{
+		{
+			desc:     "gitlab.com",
+			baseURL:  "https://gitlab.com/api/v4",
+			webURL:   "https://gitlab.com/traefik/traefik/-/merge_requests/1",
+			expected: "traefik/traefik",
+		},
+		{
+			desc:     "subgroup",
+			baseURL:  "https://gitlab.com/api/v4",
+			webURL:   "https://gitlab.com/traefik/sub/traefik/-/merge_requests/1",
+			expected: "traefik/sub/traefik",
+		},
+		{
+			desc:     "relative URL root",
+			baseURL:  "https://example.com/gitlab/api/v4",
+			webURL:   "https://example.com/gitlab/traefik/traefik/merge_requests/1",
+			expected: "traefik/traefik",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := gitlab.NewClient("", gitlab.WithBaseURL(test.baseURL))
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, NewGitLab(client).getProjectPath(test.webURL))
+		})
+	}
+}
+
+func Test_pipelineState(t *testing.T) {
+	testCases := []struct {
+		status   string
+		expected string
+		errored  bool
+	}{
+		{status: "success", expected: Success},
+		{status: "skipped", expected: Success},
+		{status: "manual", expected: Success},
+		{status: "running", expected: Pending},
+		{status: "created", expected: Pending},
+		{status: "failed", errored: true},
+		{status: "canceled", errored: true},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.status, func(t *testing.T) {
+			t.Parallel()
+
+			state, err := pipelineState(&gitlab.PipelineInfo{Status: test.status})
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, state)
+		})
+	}
+}
+
+func Test_toPullRequest(t *testing.T) {
+	target := &gitlab.Project{
+		Path:              "traefik",
+		PathWithNamespace: "traefik/traefik",
+		Visibility:        gitlab.PublicVisibility,
+		HTTPURLToRepo:     "https://gitlab.com/traefik/traefik.git",
+		Namespace:         &gitlab.ProjectNamespace{FullPath: "traefik"},
+	}
+
+	source := &gitlab.Project{
+		Path:              "traefik",
+		PathWithNamespace: "ldez/traefik",
+		Visibility:        gitlab.PrivateVisibility,
+		HTTPURLToRepo:     "https://gitlab.com/ldez/traefik.git",
+		Namespace:         &gitlab.ProjectNamespace{FullPath: "ldez"},
+	}
+
+	mr := &gitlab.MergeRequest{
+		IID:          12,
+		Title:        "Fix the bug",
+		State:        "opened",
+		MergeStatus:  "can_be_merged",
+		TargetBranch: "master",
+		SourceBranch: "fix",
+		SHA:          "abc",
+		Labels:       gitlab.Labels{"status/3-needs-merge"},
+	}
+
+	pr := toPullRequest(mr, target, source)
+
+	assert.Equal(t, 12, pr.GetNumber())
+	assert.Equal(t, "open", pr.GetState())
+	assert.True(t, pr.GetMergeable())
+	assert.False(t, pr.GetMerged())
+	assert.Equal(t, []*github.Label{{Name: github.String("status/3-needs-merge")}}, pr.Labels)
+
+	assert.Equal(t, "master", pr.Base.GetRef())
+	assert.Equal(t, "traefik", pr.Base.Repo.Owner.GetLogin())
+	assert.False(t, pr.Base.Repo.GetPrivate())
+
+	assert.Equal(t, "ldez/traefik:fix", pr.Head.GetLabel())
+	assert.Equal(t, "ldez", pr.Head.User.GetLogin())
+	assert.Equal(t, "abc", pr.Head.GetSHA())
+	assert.Equal(t, "https://gitlab.com/ldez/traefik.git", pr.Head.Repo.GetGitURL())
+	assert.True(t, pr.Head.Repo.GetPrivate())
+}
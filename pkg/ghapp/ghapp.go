@@ -0,0 +1,208 @@
+// Package ghapp authenticates the bot as a GitHub App.
+//
+// https://docs.github.com/en/developers/apps/building-github-apps/authenticating-with-github-apps
+package ghapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// jwtLifetime the lifetime of the JWT of the app (10 minutes max).
+	jwtLifetime = 9 * time.Minute
+	// clockDrift allowance for the clock drift between the bot and GitHub.
+	clockDrift = 1 * time.Minute
+	// refreshMargin an installation token is refreshed before its expiration (1 hour),
+	// to ensure that a token used by git stays valid until the end of a process.
+	refreshMargin = 10 * time.Minute
+)
+
+// App a GitHub App.
+type App struct {
+	client *github.Client
+
+	mu      sync.Mutex
+	sources map[string]oauth2.TokenSource
+}
+
+// New creates a new GitHub App.
+// The privateKey is the content of the PEM file of the app.
+func New(appID int64, privateKey []byte, gitHubURL string, base http.RoundTripper) (*App, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	client := github.NewClient(&http.Client{Transport: &Transport{appID: appID, key: key, base: base}})
+
+	if gitHubURL != "" {
+		baseURL, err := url.Parse(gitHubURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub URL: %w", err)
+		}
+
+		client.BaseURL = baseURL
+	}
+
+	return &App{client: client, sources: make(map[string]oauth2.TokenSource)}, nil
+}
+
+// TokenSource gets the source of the installation tokens of an owner (user or organization).
+// The tokens are reused until they expire.
+func (a *App) TokenSource(ctx context.Context, owner string) oauth2.TokenSource {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ts, ok := a.sources[owner]; ok {
+		return ts
+	}
+
+	ts := oauth2.ReuseTokenSource(nil, &installationTokenSource{ctx: ctx, client: a.client, owner: owner})
+	a.sources[owner] = ts
+
+	return ts
+}
+
+// installationTokenSource creates the installation tokens of an owner.
+type installationTokenSource struct {
+	ctx    context.Context
+	client *github.Client
+	owner  string
+
+	installationID int64
+}
+
+// Token implements oauth2.TokenSource.
+// The calls are serialized by oauth2.ReuseTokenSource.
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	if s.installationID == 0 {
+		id, err := s.findInstallation()
+		if err != nil {
+			return nil, err
+		}
+
+		s.installationID = id
+	}
+
+	token, _, err := s.client.Apps.CreateInstallationToken(s.ctx, s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an installation token for %s: %w", s.owner, err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Add(-refreshMargin),
+	}, nil
+}
+
+// findInstallation finds the installation of the app on an organization, or on a user.
+func (s *installationTokenSource) findInstallation() (int64, error) {
+	installation, resp, err := s.client.Apps.FindOrganizationInstallation(s.ctx, s.owner)
+	if err == nil {
+		return installation.GetID(), nil
+	}
+
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return 0, fmt.Errorf("failed to find the installation for %s: %w", s.owner, err)
+	}
+
+	installation, _, err = s.client.Apps.FindUserInstallation(s.ctx, s.owner)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the installation for %s: %w", s.owner, err)
+	}
+
+	return installation.GetID(), nil
+}
+
+// Transport an HTTP transport that authenticates the requests as a GitHub App (JWT).
+type Transport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := signJWT(t.appID, t.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return t.base.RoundTrip(r)
+}
+
+// signJWT creates the JWT (RS256) of an app.
+func signJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-clockDrift).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey parses an RSA private key (PKCS#1 or PKCS#8) in PEM format.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid private key: no PEM block")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid private key: not an RSA key")
+	}
+
+	return rsaKey, nil
+}
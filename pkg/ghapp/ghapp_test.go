@@ -0,0 +1,143 @@
+package ghapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_signJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	token, err := signJWT(42, key, now)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature)
+	require.NoError(t, err)
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	claims := map[string]int64{}
+	err = json.Unmarshal(data, &claims)
+	require.NoError(t, err)
+
+	expected := map[string]int64{
+		"iss": 42,
+		"iat": now.Add(-clockDrift).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+	}
+	assert.Equal(t, expected, claims)
+}
+
+func Test_parsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc    string
+		data    []byte
+		errored bool
+	}{
+		{
+			desc: "PKCS#1",
+			data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+		{
+			desc: "PKCS#8",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			desc:    "not PEM",
+			data:    []byte("foo"),
+			errored: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			parsed, err := parsePrivateKey(test.data)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, key.Equal(parsed))
+		})
+	}
+}
+
+func TestApp_TokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var created int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/ldez/installation", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/users/ldez/installation", func(rw http.ResponseWriter, req *http.Request) {
+		assert.True(t, strings.HasPrefix(req.Header.Get("Authorization"), "Bearer "))
+
+		_, _ = rw.Write([]byte(`{"id": 7}`))
+	})
+	mux.HandleFunc("/app/installations/7/access_tokens", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+
+		created++
+
+		expiresAt := time.Now().Add(1 * time.Hour).UTC().Format(time.RFC3339)
+		_, _ = rw.Write([]byte(`{"token": "secret", "expires_at": "` + expiresAt + `"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	app, err := New(42, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), server.URL+"/", nil)
+	require.NoError(t, err)
+
+	ts := app.TokenSource(context.Background(), "ldez")
+
+	token, err := ts.Token()
+	require.NoError(t, err)
+
+	assert.Equal(t, "secret", token.AccessToken)
+
+	// the token is reused.
+	_, err = app.TokenSource(context.Background(), "ldez").Token()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, created)
+}
@@ -0,0 +1,100 @@
+// Package metrics the Prometheus metrics of the bot.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lobicornis"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// Merged the pull requests merged, by repository and merge method (or queue for the merge queue).
+	Merged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pull_requests_merged_total",
+		Help:      "The number of pull requests merged.",
+	}, []string{"repo", "reason"})
+
+	// Updated the pull requests updated, by repository and update action (rebase, merge, forge).
+	Updated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pull_requests_updated_total",
+		Help:      "The number of pull requests updated.",
+	}, []string{"repo", "reason"})
+
+	// Escalated the pull requests escalated to a human (markers.needHumanMerge), by repository and reason.
+	Escalated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pull_requests_escalated_total",
+		Help:      "The number of pull requests escalated to a human.",
+	}, []string{"repo", "reason"})
+
+	// SweepDuration the duration of the sweeps.
+	SweepDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sweep_duration_seconds",
+		Help:      "The duration of the sweeps of the repositories.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200},
+	})
+
+	// CloneDuration the duration of the clones of the repositories.
+	CloneDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "clone_duration_seconds",
+		Help:      "The duration of the clones of the repositories.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	})
+
+	// TimeToMerge the time between the addition of the label markers.needMerge and the merge.
+	TimeToMerge = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "time_to_merge_seconds",
+		Help:      "The time between the addition of the merge label and the merge of the pull requests.",
+		Buckets:   []float64{60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600, 12 * 3600, 24 * 3600, 3 * 24 * 3600, 7 * 24 * 3600},
+	})
+
+	// QueueDepth the number of pull requests waiting to be merged, by repository.
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "The number of pull requests waiting to be merged.",
+	}, []string{"repo"})
+
+	// RateLimitRemaining the remaining requests of the GitHub rate limit, by owner (each owner can have its own token) and resource (core, search).
+	RateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "github_rate_limit_remaining",
+		Help:      "The remaining requests of the GitHub rate limit.",
+	}, []string{"owner", "resource"})
+)
+
+func init() {
+	registry.MustRegister(
+		Merged,
+		Updated,
+		Escalated,
+		SweepDuration,
+		CloneDuration,
+		TimeToMerge,
+		QueueDepth,
+		RateLimitRemaining,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler the HTTP handler of the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Since observes the duration since start.
+func Since(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	Merged.WithLabelValues("foo/bar", "squash").Inc()
+	QueueDepth.WithLabelValues("foo/bar").Set(3)
+
+	server := httptest.NewServer(Handler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `lobicornis_pull_requests_merged_total{reason="squash",repo="foo/bar"} 1`)
+	assert.Contains(t, string(body), `lobicornis_queue_depth{repo="foo/bar"} 3`)
+	assert.Contains(t, string(body), "lobicornis_sweep_duration_seconds_bucket")
+}
@@ -0,0 +1,188 @@
+// Package notify the notifications of the outcomes of the pull requests (Slack, Mattermost, Microsoft Teams, or a generic webhook).
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// defaultTemplate the default template of the messages.
+// The author is pinged only if the notifier knows its chat handle.
+const defaultTemplate = `[{{ .Repo }}] #{{ .PR }} {{ .Title }}: {{ .Type }}{{ with .Detail }} ({{ . }}){{ end }}
+{{ .URL }}{{ with .AuthorHandle }} {{ . }}{{ end }}{{ with .Mentions }} {{ join . " " }}{{ end }}`
+
+// Event an event of a pull request.
+type Event struct {
+	// Type merged, updated, escalated, or retryExhausted.
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Repo   string    `json:"repo"`
+	PR     int       `json:"pr"`
+	Title  string    `json:"title"`
+	URL    string    `json:"url"`
+	Author string    `json:"author"`
+	// Mentions the users mentioned in the notifications (mentions of the repository configuration).
+	Mentions []string `json:"mentions,omitempty"`
+	// Detail the merge method (merged), the update action (updated), or the error (escalated, retryExhausted).
+	Detail string `json:"detail,omitempty"`
+}
+
+// message the data of the template of a message.
+type message struct {
+	Event
+
+	// AuthorHandle the chat handle of the author (handles of the notifier), empty if unknown.
+	AuthorHandle string
+}
+
+type sink struct {
+	typ    string
+	url    string
+	events []string
+	tmpl   *template.Template
+	// handles the chat handles by login (lower case).
+	handles map[string]string
+}
+
+// Notifier sends the events to the sinks.
+type Notifier struct {
+	client *http.Client
+	sinks  map[string]sink
+}
+
+// New creates a new notifier.
+// Returns nil if there is no sink.
+func New(client *http.Client, notifiers map[string]conf.Notifier) (*Notifier, error) {
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+
+	n := &Notifier{client: client, sinks: make(map[string]sink)}
+
+	for name, cfg := range notifiers {
+		text := cfg.Template
+		if text == "" {
+			text = defaultTemplate
+		}
+
+		tmpl, err := template.New(name).Funcs(conf.NotifierFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: invalid template: %w", name, err)
+		}
+
+		handles := make(map[string]string, len(cfg.Handles))
+		for login, handle := range cfg.Handles {
+			handles[strings.ToLower(login)] = handle
+		}
+
+		n.sinks[name] = sink{typ: cfg.Type, url: cfg.URL, events: cfg.Events, tmpl: tmpl, handles: handles}
+	}
+
+	return n, nil
+}
+
+// Notify sends an event to the sinks (names) subscribed to the type of the event.
+// All the sinks are notified, even if one of them fails.
+func (n *Notifier) Notify(ctx context.Context, names []string, event Event) error {
+	if n == nil {
+		return nil
+	}
+
+	var errs []string
+	for _, name := range names {
+		s, ok := n.sinks[name]
+		if !ok || !s.accept(event.Type) {
+			continue
+		}
+
+		err := n.send(ctx, s, event)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to notify: %s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+func (n *Notifier) send(ctx context.Context, s sink, event Event) error {
+	payload, err := s.payload(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// the URL contains the secret of the incoming webhook.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s sink) accept(eventType string) bool {
+	if len(s.events) == 0 {
+		return true
+	}
+
+	for _, e := range s.events {
+		if e == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// payload creates the body of the request: the event for the type webhook, else the message.
+func (s sink) payload(event Event) ([]byte, error) {
+	if s.typ == conf.NotifierWebhook {
+		return json.Marshal(event)
+	}
+
+	data := message{Event: event, AuthorHandle: s.handles[strings.ToLower(event.Author)]}
+
+	var text bytes.Buffer
+	err := s.tmpl.Execute(&text, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute the template: %w", err)
+	}
+
+	// the incoming webhooks of Slack, Mattermost, and Microsoft Teams accept a text message.
+	return json.Marshal(map[string]string{"text": text.String()})
+}
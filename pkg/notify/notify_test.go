@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func TestNotifier_Notify(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string][]string)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(_ http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		mu.Lock()
+		bodies[req.URL.Path] = append(bodies[req.URL.Path], string(body))
+		mu.Unlock()
+	})
+	mux.HandleFunc("/error", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	notifiers := map[string]conf.Notifier{
+		"slack": {
+			Type:    conf.NotifierSlack,
+			URL:     server.URL + "/slack",
+			Handles: map[string]string{"Alice": "<@U012AB3CD>"},
+		},
+		"mattermost": {
+			Type: conf.NotifierMattermost,
+			URL:  server.URL + "/mattermost",
+		},
+		"teams": {
+			Type:     conf.NotifierTeams,
+			URL:      server.URL + "/teams",
+			Events:   []string{conf.EventEscalated},
+			Template: `{{ .Repo }}#{{ .PR }} {{ .Type }} {{ join .Mentions ", " }}`,
+		},
+		"webhook": {
+			Type: conf.NotifierWebhook,
+			URL:  server.URL + "/webhook",
+		},
+		"broken": {
+			Type: conf.NotifierMattermost,
+			URL:  server.URL + "/error",
+		},
+	}
+
+	notifier, err := New(server.Client(), notifiers)
+	require.NoError(t, err)
+
+	event := Event{
+		Type:     conf.EventMerged,
+		Time:     time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC),
+		Repo:     "foo/bar",
+		PR:       1,
+		Title:    "Fix the bug",
+		URL:      "https://github.com/foo/bar/pull/1",
+		Author:   "alice",
+		Mentions: []string{"@bob", "@carol"},
+		Detail:   conf.MergeMethodSquash,
+	}
+
+	err = notifier.Notify(context.Background(), []string{"slack", "mattermost", "teams", "webhook", "unknown"}, event)
+	require.NoError(t, err)
+
+	event.Type = conf.EventEscalated
+	event.Detail = "the milestone is missing"
+
+	err = notifier.Notify(context.Background(), []string{"teams", "broken"}, event)
+	require.EqualError(t, err, "unable to notify: broken: unexpected status code: 500")
+
+	assert.Equal(t, []string{`{"text":"[foo/bar] #1 Fix the bug: merged (squash)\nhttps://github.com/foo/bar/pull/1 \u003c@U012AB3CD\u003e @bob @carol"}`}, bodies["/slack"])
+	// the author without a chat handle is not pinged.
+	assert.Equal(t, []string{`{"text":"[foo/bar] #1 Fix the bug: merged (squash)\nhttps://github.com/foo/bar/pull/1 @bob @carol"}`}, bodies["/mattermost"])
+	assert.Equal(t, []string{`{"text":"foo/bar#1 escalated @bob, @carol"}`}, bodies["/teams"])
+
+	require.Len(t, bodies["/webhook"], 1)
+
+	var received Event
+	err = json.Unmarshal([]byte(bodies["/webhook"][0]), &received)
+	require.NoError(t, err)
+
+	event.Type = conf.EventMerged
+	event.Detail = conf.MergeMethodSquash
+	assert.Equal(t, event, received)
+}
+
+func TestNotifier_Notify_hideURL(t *testing.T) {
+	notifier, err := New(http.DefaultClient, map[string]conf.Notifier{
+		"slack": {Type: conf.NotifierSlack, URL: "http://127.0.0.1:0/services/secret"},
+	})
+	require.NoError(t, err)
+
+	err = notifier.Notify(context.Background(), []string{"slack"}, Event{Type: conf.EventMerged})
+	require.Error(t, err)
+
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestNew(t *testing.T) {
+	notifier, err := New(http.DefaultClient, nil)
+	require.NoError(t, err)
+	require.Nil(t, notifier)
+
+	// a nil notifier does nothing.
+	assert.NoError(t, notifier.Notify(context.Background(), []string{"slack"}, Event{Type: conf.EventMerged}))
+
+	_, err = New(http.DefaultClient, map[string]conf.Notifier{"slack": {Template: "{{ .Repo "}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInterval the minimal time between 2 mutative requests recommended by GitHub.
+	DefaultInterval = 1 * time.Second
+
+	maxRetryAfter = 1 * time.Minute
+)
+
+// Transport an HTTP transport that respects the GitHub secondary rate limits.
+//   - the mutative requests (POST, PATCH, PUT, DELETE) are serialized and spaced out.
+//   - a request rejected by a secondary rate limit is retried once, after the delay defined by the Retry-After header.
+//
+// https://docs.github.com/en/rest/guides/best-practices-for-integrators#dealing-with-secondary-rate-limits
+type Transport struct {
+	base     http.RoundTripper
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewTransport creates a new Transport.
+func NewTransport(base http.RoundTripper, interval time.Duration) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{base: base, interval: interval}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isMutative(req.Method) {
+		err := t.wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	delay, ok := getRetryAfter(resp)
+	if !ok || !isReplayable(req) {
+		return resp, nil
+	}
+
+	_ = resp.Body.Close()
+
+	err = sleep(req.Context(), delay)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(retry)
+}
+
+// wait waits for the end of the interval since the previous mutative request.
+func (t *Transport) wait(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := sleep(ctx, time.Until(t.last.Add(t.interval)))
+	if err != nil {
+		return err
+	}
+
+	t.last = time.Now()
+
+	return nil
+}
+
+func isMutative(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// getRetryAfter gets the delay before a retry when a secondary rate limit is reached.
+func getRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	delay := time.Duration(seconds) * time.Second
+	if delay > maxRetryAfter {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
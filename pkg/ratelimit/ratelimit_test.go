@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport_RoundTrip_spacing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: NewTransport(nil, 50*time.Millisecond)}
+
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	// the read requests are not spaced out.
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+
+	start = time.Now()
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+}
+
+func TestTransport_RoundTrip_retryAfter(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, `{"foo":"bar"}`, string(body))
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			rw.Header().Set("Retry-After", "0")
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: NewTransport(nil, 0)}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"foo":"bar"}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestTransport_RoundTrip_forbidden(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: NewTransport(nil, 0)}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
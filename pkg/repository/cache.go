@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ldez/go-git-cmd-wrapper/fetch"
+	"github.com/ldez/go-git-cmd-wrapper/git"
+	ginit "github.com/ldez/go-git-cmd-wrapper/init"
+	"github.com/ldez/go-git-cmd-wrapper/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// Cache a persistent cache of bare mirrors, keyed by repository.
+// The mirrors are fetched incrementally,
+// and the working copy of each git operation borrows the objects of a mirror (git alternates) instead of downloading them.
+type Cache struct {
+	dir     string
+	maxAge  time.Duration
+	maxSize int64
+
+	debug bool
+
+	mu sync.Mutex
+	// users the number of workspaces using a mirror.
+	users map[string]int
+	// locks serializes the updates of a mirror.
+	locks map[string]*sync.Mutex
+	// mirrors the size and the last use of the mirrors.
+	// Loaded from the disk once, then each update measures only the updated mirror.
+	mirrors  map[string]*cacheEntry
+	loadOnce sync.Once
+}
+
+// NewCache creates a new cache.
+// Returns nil if the cache is disabled.
+func NewCache(cfg conf.GitCache) *Cache {
+	if cfg.Dir == "" {
+		return nil
+	}
+
+	// the path of the mirrors is used from the sandbox directories.
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		dir = cfg.Dir
+	}
+
+	return &Cache{
+		dir:     dir,
+		maxAge:  cfg.MaxAge,
+		maxSize: int64(cfg.MaxSize) << 20,
+		debug:   log.Logger.GetLevel() == zerolog.DebugLevel,
+		users:   make(map[string]int),
+		locks:   make(map[string]*sync.Mutex),
+		mirrors: make(map[string]*cacheEntry),
+	}
+}
+
+// mirror updates the mirror of a repository and returns its path.
+// The mirror cannot be evicted until the workspace is removed.
+func (c *Cache) mirror(ctx context.Context, ws *Workspace, key, url string) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+
+	c.loadOnce.Do(func() { c.load(ctx) })
+
+	c.mu.Lock()
+	c.users[key]++
+	lock, ok := c.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.locks[key] = lock
+	}
+	c.mu.Unlock()
+
+	ws.onRemove(func() { c.release(key) })
+
+	path := filepath.Join(c.dir, filepath.FromSlash(key))
+
+	lock.Lock()
+	output, err := c.update(ctx, path, url)
+	if err != nil {
+		lock.Unlock()
+		return "", fmt.Errorf("failed to update the mirror %s: %w\n %s", key, err, output)
+	}
+
+	size, err := dirSize(path)
+	lock.Unlock()
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get the size of the mirror %s: %w", key, err)
+	}
+
+	c.mu.Lock()
+	c.mirrors[key] = &cacheEntry{key: key, path: path, modTime: time.Now(), size: size}
+	c.mu.Unlock()
+
+	c.evict(ctx)
+
+	return path, nil
+}
+
+func (c *Cache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.users[key]--
+	if c.users[key] <= 0 {
+		delete(c.users, key)
+	}
+}
+
+// update creates the mirror if needed, and fetches the branches.
+// The URL is never stored in the mirror: it can contain a token.
+func (c *Cache) update(ctx context.Context, path, url string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		err = os.MkdirAll(path, 0o700)
+		if err != nil {
+			return "", err
+		}
+
+		output, err := git.Init(ginit.Bare, ginit.Quiet, git.Debugger(c.debug), dirExecutor(ctx, path))
+		if err != nil {
+			_ = os.RemoveAll(path)
+			return output, err
+		}
+	}
+
+	output, err := git.Fetch(
+		fetch.NoTags,
+		fetch.Prune,
+		fetch.Quiet,
+		fetch.Remote(url),
+		fetch.RefSpec("+refs/heads/*:refs/heads/*"),
+		git.Debugger(c.debug),
+		dirExecutor(ctx, path))
+	if err != nil {
+		return output, err
+	}
+
+	now := time.Now()
+
+	return "", os.Chtimes(path, now, now)
+}
+
+// load reads the mirrors of the previous runs from the disk.
+func (c *Cache) load(ctx context.Context) {
+	entries, err := scanMirrors(c.dir)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("unable to list the cache entries")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range entries {
+		if _, ok := c.mirrors[entry.key]; !ok {
+			c.mirrors[entry.key] = entry
+		}
+	}
+}
+
+type cacheEntry struct {
+	key     string
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// evict removes the unused mirrors that are too old, then the least recently used mirrors until the cache size fits.
+func (c *Cache) evict(ctx context.Context) {
+	if c.maxAge <= 0 && c.maxSize <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]*cacheEntry, 0, len(c.mirrors))
+	for _, entry := range c.mirrors {
+		entries = append(entries, entry)
+	}
+
+	// the least recently used first.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.size
+	}
+
+	for _, entry := range entries {
+		tooOld := c.maxAge > 0 && time.Since(entry.modTime) > c.maxAge
+		tooBig := c.maxSize > 0 && total > c.maxSize
+
+		if !tooOld && !tooBig {
+			continue
+		}
+
+		if c.users[entry.key] > 0 {
+			continue
+		}
+
+		log.Ctx(ctx).Debug().Str("mirror", entry.key).Msg("Evict the mirror from the cache.")
+
+		err := os.RemoveAll(entry.path)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("mirror", entry.key).Msg("unable to evict the mirror")
+			continue
+		}
+
+		delete(c.mirrors, entry.key)
+		total -= entry.size
+	}
+}
+
+// scanMirrors lists the mirrors of a directory: <dir>/<host>/<path>.git
+// The path of a repository can have several segments (ex: GitLab subgroups).
+func scanMirrors(dir string) ([]*cacheEntry, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var entries []*cacheEntry
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == dir || !info.IsDir() || !strings.HasSuffix(info.Name(), ".git") {
+			return nil
+		}
+
+		size, err := dirSize(path)
+		if err != nil {
+			return err
+		}
+
+		key, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, &cacheEntry{
+			key:     filepath.ToSlash(key),
+			path:    path,
+			modTime: info.ModTime(),
+			size:    size,
+		})
+
+		return filepath.SkipDir
+	})
+
+	return entries, err
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// cacheKey gets the cache key of a repository from its git URL.
+// ex: git://github.com/traefik/traefik.git -> github.com/traefik/traefik.git
+func cacheKey(gitURL string) string {
+	if i := strings.Index(gitURL, "://"); i >= 0 {
+		gitURL = gitURL[i+3:]
+	}
+
+	if !strings.HasSuffix(gitURL, ".git") {
+		gitURL += ".git"
+	}
+
+	return gitURL
+}
+
+// withReference borrows the objects of a mirror during a clone.
+func withReference(path string) types.Option {
+	return func(g *types.Cmd) {
+		g.AddOptions("--reference")
+		g.AddOptions(path)
+	}
+}
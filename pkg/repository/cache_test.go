@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ldez/go-git-cmd-wrapper/clone"
+	"github.com/ldez/go-git-cmd-wrapper/commit"
+	"github.com/ldez/go-git-cmd-wrapper/git"
+	ginit "github.com/ldez/go-git-cmd-wrapper/init"
+	"github.com/ldez/go-git-cmd-wrapper/revparse"
+	"github.com/ldez/go-git-cmd-wrapper/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func TestCache_mirror(t *testing.T) {
+	ctx := context.Background()
+
+	origin := createRepository(t)
+
+	cache := NewCache(conf.GitCache{Dir: tempDir(t)})
+
+	ws, err := NewWorkspace("")
+	require.NoError(t, err)
+
+	path, err := cache.mirror(ctx, ws, "example.com/foo/bar.git", origin)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(cache.dir, "example.com", "foo", "bar.git"), path)
+	assert.Equal(t, 1, cache.users["example.com/foo/bar.git"])
+
+	output, err := git.Clone(withReference(path), clone.Repository(origin), clone.Directory("."), ws.executor(ctx))
+	require.NoError(t, err, output)
+
+	assert.FileExists(t, filepath.Join(ws.Dir(), ".git", "objects", "info", "alternates"))
+
+	// the URL is not stored in the mirror.
+	output, err = git.Raw("config", func(g *types.Cmd) {
+		g.AddOptions("--list")
+	}, dirExecutor(ctx, path))
+	require.NoError(t, err, output)
+	assert.NotContains(t, output, origin)
+
+	err = ws.Remove()
+	require.NoError(t, err)
+
+	assert.Empty(t, cache.users)
+
+	// incremental fetch.
+	commitEmpty(t, origin, "second")
+
+	ws, err = NewWorkspace("")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ws.Remove() })
+
+	_, err = cache.mirror(ctx, ws, "example.com/foo/bar.git", origin)
+	require.NoError(t, err)
+
+	expected, err := git.RevParse(revparse.Args("HEAD"), dirExecutor(ctx, origin))
+	require.NoError(t, err)
+
+	actual, err := git.RevParse(revparse.Args("refs/heads/master"), dirExecutor(ctx, path))
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(actual))
+}
+
+func TestCache_evict(t *testing.T) {
+	ctx := context.Background()
+
+	origin := createRepository(t)
+
+	cache := NewCache(conf.GitCache{Dir: tempDir(t), MaxAge: time.Hour})
+
+	keys := []string{"example.com/foo/a.git", "example.com/foo/b.git", "example.com/foo/c.git"}
+
+	for _, key := range keys {
+		ws, err := NewWorkspace("")
+		require.NoError(t, err)
+
+		_, err = cache.mirror(ctx, ws, key, origin)
+		require.NoError(t, err)
+
+		if key != "example.com/foo/c.git" {
+			require.NoError(t, ws.Remove())
+		} else {
+			t.Cleanup(func() { _ = ws.Remove() })
+		}
+
+		cache.mirrors[key].modTime = time.Now().Add(-2 * time.Hour)
+	}
+
+	// a too old mirror used by a workspace is kept.
+	cache.evict(ctx)
+
+	assertMirrors(t, cache, "example.com/foo/c.git")
+
+	// the size limit.
+	cache.maxAge = 0
+	cache.maxSize = 1
+
+	ws, err := NewWorkspace("")
+	require.NoError(t, err)
+
+	_, err = cache.mirror(ctx, ws, "example.com/foo/d.git", origin)
+	require.NoError(t, err)
+
+	require.NoError(t, ws.Remove())
+
+	cache.evict(ctx)
+
+	assertMirrors(t, cache, "example.com/foo/c.git")
+}
+
+func TestCache_load(t *testing.T) {
+	ctx := context.Background()
+
+	origin := createRepository(t)
+
+	dir := tempDir(t)
+
+	previous := NewCache(conf.GitCache{Dir: dir})
+
+	// GitLab subgroups.
+	keys := []string{"example.com/foo/a.git", "example.com/foo/bar/b.git", "example.com/foo/bar/baz/c.git"}
+
+	for _, key := range keys {
+		ws, err := NewWorkspace("")
+		require.NoError(t, err)
+
+		_, err = previous.mirror(ctx, ws, key, origin)
+		require.NoError(t, err)
+
+		require.NoError(t, ws.Remove())
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "example.com", "foo", "bar", "b.git"), old, old))
+
+	cache := NewCache(conf.GitCache{Dir: dir, MaxAge: time.Hour})
+
+	ws, err := NewWorkspace("")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ws.Remove() })
+
+	_, err = cache.mirror(ctx, ws, "example.com/foo/a.git", origin)
+	require.NoError(t, err)
+
+	assertMirrors(t, cache, "example.com/foo/a.git", "example.com/foo/bar/baz/c.git")
+	assert.Positive(t, cache.mirrors["example.com/foo/bar/baz/c.git"].size)
+}
+
+// assertMirrors checks the mirrors tracked by the cache, and the mirrors on the disk.
+func assertMirrors(t *testing.T, cache *Cache, expected ...string) {
+	t.Helper()
+
+	var tracked []string
+	for key := range cache.mirrors {
+		tracked = append(tracked, key)
+	}
+
+	assert.ElementsMatch(t, expected, tracked)
+
+	entries, err := scanMirrors(cache.dir)
+	require.NoError(t, err)
+
+	var onDisk []string
+	for _, entry := range entries {
+		onDisk = append(onDisk, entry.key)
+	}
+
+	assert.ElementsMatch(t, expected, onDisk)
+}
+
+func Test_cacheKey(t *testing.T) {
+	assert.Equal(t, "github.com/traefik/traefik.git", cacheKey("git://github.com/traefik/traefik.git"))
+	assert.Equal(t, "github.com/traefik/traefik.git", cacheKey("https://github.com/traefik/traefik"))
+}
+
+func Test_nilCache(t *testing.T) {
+	var cache *Cache
+
+	path, err := cache.mirror(context.Background(), &Workspace{}, "example.com/foo/bar.git", "")
+	require.NoError(t, err)
+
+	assert.Empty(t, path)
+}
+
+// createRepository creates a local repository with one commit.
+func createRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := tempDir(t)
+
+	output, err := git.Init(ginit.Directory("."), dirExecutor(context.Background(), dir))
+	require.NoError(t, err, output)
+
+	output, err = git.Raw("symbolic-ref", func(g *types.Cmd) {
+		g.AddOptions("HEAD")
+		g.AddOptions("refs/heads/master")
+	}, dirExecutor(context.Background(), dir))
+	require.NoError(t, err, output)
+
+	output, err = configureGitUserInfo(context.Background(), "hubert", "hubert@foo.com", &Workspace{dir: dir})
+	require.NoError(t, err, output)
+
+	commitEmpty(t, dir, "first")
+
+	return dir
+}
+
+func commitEmpty(t *testing.T, dir, message string) {
+	t.Helper()
+
+	output, err := git.Commit(commit.AllowEmpty, commit.Message(message), dirExecutor(context.Background(), dir))
+	require.NoError(t, err, output)
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "lobicornis-test")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	return dir
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v32/github"
 	"github.com/ldez/go-git-cmd-wrapper/checkout"
@@ -15,6 +16,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
 )
 
 type remoteModel struct {
@@ -32,19 +34,21 @@ type prModel struct {
 type Clone struct {
 	git   conf.Git
 	token string
+	cache *Cache
 	debug bool
 }
 
-func newClone(gitConfig conf.Git, token string) Clone {
+func newClone(gitConfig conf.Git, token string, cache *Cache) Clone {
 	return Clone{
 		git:   gitConfig,
 		token: token,
+		cache: cache,
 		debug: log.Logger.GetLevel() == zerolog.DebugLevel,
 	}
 }
 
 // PullRequestForMerge Clone a pull request for a merge.
-func (c Clone) PullRequestForMerge(ctx context.Context, pr *github.PullRequest) (string, error) {
+func (c Clone) PullRequestForMerge(ctx context.Context, pr *github.PullRequest, ws *Workspace) (string, error) {
 	var forkURL string
 	if pr.Base.Repo.GetPrivate() {
 		forkURL = makeRepositoryURL(pr.Head.Repo.GetGitURL(), c.git.SSH, c.token)
@@ -66,11 +70,11 @@ func (c Clone) PullRequestForMerge(ctx context.Context, pr *github.PullRequest)
 		},
 	}
 
-	return c.pullRequest(ctx, pr, model)
+	return c.pullRequest(ctx, pr, model, ws)
 }
 
 // PullRequestForUpdate Clone a pull request for an update (rebase).
-func (c Clone) PullRequestForUpdate(ctx context.Context, pr *github.PullRequest) (string, error) {
+func (c Clone) PullRequestForUpdate(ctx context.Context, pr *github.PullRequest, ws *Workspace) (string, error) {
 	var unchangedURL string
 	if pr.Base.Repo.GetPrivate() {
 		unchangedURL = makeRepositoryURL(pr.Base.Repo.GetGitURL(), c.git.SSH, c.token)
@@ -92,18 +96,48 @@ func (c Clone) PullRequestForUpdate(ctx context.Context, pr *github.PullRequest)
 		},
 	}
 
-	return c.pullRequest(ctx, pr, model)
+	return c.pullRequest(ctx, pr, model, ws)
 }
 
-func (c Clone) pullRequest(ctx context.Context, pr *github.PullRequest, prModel prModel) (string, error) {
+// BaseBranch Clone the base branch of a pull request (merge queue).
+func (c Clone) BaseBranch(ctx context.Context, pr *github.PullRequest, ws *Workspace) (string, error) {
+	defer metrics.Since(metrics.CloneDuration, time.Now())
+
+	baseURL := pr.Base.Repo.GetGitURL()
+
+	reference, err := c.cache.mirror(ctx, ws, cacheKey(baseURL), makeRepositoryURL(baseURL, c.git.SSH, c.token))
+	if err != nil {
+		// the cache is only an optimization.
+		log.Ctx(ctx).Warn().Err(err).Msg("Unable to use the cache.")
+	}
+
+	model := remoteModel{
+		url: makeRepositoryURL(baseURL, c.git.SSH, c.token),
+		ref: pr.Base.GetRef(),
+	}
+
+	return c.fromMainRepository(ctx, model, reference, ws)
+}
+
+func (c Clone) pullRequest(ctx context.Context, pr *github.PullRequest, prModel prModel, ws *Workspace) (string, error) {
+	defer metrics.Since(metrics.CloneDuration, time.Now())
+
 	logger := log.Ctx(ctx)
 
+	baseURL := pr.Base.Repo.GetGitURL()
+
+	reference, err := c.cache.mirror(ctx, ws, cacheKey(baseURL), makeRepositoryURL(baseURL, c.git.SSH, c.token))
+	if err != nil {
+		// the cache is only an optimization.
+		logger.Warn().Err(err).Msg("Unable to use the cache.")
+	}
+
 	if isOnMainRepository(pr) {
 		logger.Info().Msg("It's not a fork, it's a branch on the main repository.")
 
 		remoteName := RemoteOrigin
 
-		output, err := c.fromMainRepository(prModel.changed)
+		output, err := c.fromMainRepository(ctx, prModel.changed, reference, ws)
 		if err != nil {
 			logger.Error().Err(err).Msg(output)
 			return "", err
@@ -113,7 +147,7 @@ func (c Clone) pullRequest(ctx context.Context, pr *github.PullRequest, prModel
 	}
 
 	remoteName := RemoteUpstream
-	output, err := c.fromFork(prModel.changed, prModel.unchanged, remoteName)
+	output, err := c.fromFork(ctx, prModel.changed, prModel.unchanged, remoteName, reference, ws)
 	if err != nil {
 		logger.Error().Err(err).Msg(output)
 		return "", err
@@ -122,18 +156,23 @@ func (c Clone) pullRequest(ctx context.Context, pr *github.PullRequest, prModel
 	return remoteName, nil
 }
 
-func (c Clone) fromMainRepository(remoteModel remoteModel) (string, error) {
-	output, err := git.Clone(clone.Repository(remoteModel.url), clone.Directory("."), git.Debugger(c.debug))
+func (c Clone) fromMainRepository(ctx context.Context, remoteModel remoteModel, reference string, ws *Workspace) (string, error) {
+	output, err := git.Clone(
+		git.Cond(reference != "", withReference(reference)),
+		clone.Repository(remoteModel.url),
+		clone.Directory("."),
+		git.Debugger(c.debug),
+		ws.executor(ctx))
 	if err != nil {
 		return output, err
 	}
 
-	output, err = configureGit(c.git)
+	output, err = configureGit(ctx, c.git, ws)
 	if err != nil {
 		return output, err
 	}
 
-	output, err = git.Checkout(checkout.Branch(remoteModel.ref), git.Debugger(c.debug))
+	output, err = git.Checkout(checkout.Branch(remoteModel.ref), git.Debugger(c.debug), ws.executor(ctx))
 	if err != nil {
 		return output, fmt.Errorf("failed to checkout branch %s: %w", remoteModel.ref, err)
 	}
@@ -141,27 +180,29 @@ func (c Clone) fromMainRepository(remoteModel remoteModel) (string, error) {
 	return "", nil
 }
 
-func (c Clone) fromFork(origin, upstream remoteModel, remoteName string) (string, error) {
+func (c Clone) fromFork(ctx context.Context, origin, upstream remoteModel, remoteName, reference string, ws *Workspace) (string, error) {
 	output, err := git.Clone(
+		git.Cond(reference != "", withReference(reference)),
 		clone.Repository(origin.url),
 		clone.Branch(origin.ref),
 		clone.Directory("."),
-		git.Debugger(c.debug))
+		git.Debugger(c.debug),
+		ws.executor(ctx))
 	if err != nil {
 		return output, err
 	}
 
-	output, err = configureGit(c.git)
+	output, err = configureGit(ctx, c.git, ws)
 	if err != nil {
 		return output, err
 	}
 
-	output, err = git.Remote(remote.Add(remoteName, upstream.url), git.Debugger(c.debug))
+	output, err = git.Remote(remote.Add(remoteName, upstream.url), git.Debugger(c.debug), ws.executor(ctx))
 	if err != nil {
 		return output, fmt.Errorf("failed to add remote: %w", err)
 	}
 
-	output, err = git.Fetch(fetch.NoTags, fetch.Remote(remoteName), fetch.RefSpec(upstream.ref), git.Debugger(c.debug))
+	output, err = git.Fetch(fetch.NoTags, fetch.Remote(remoteName), fetch.RefSpec(upstream.ref), git.Debugger(c.debug), ws.executor(ctx))
 	if err != nil {
 		return output, fmt.Errorf("failed to fetch %s/%s : %w", remoteName, upstream.ref, err)
 	}
@@ -170,42 +211,51 @@ func (c Clone) fromFork(origin, upstream remoteModel, remoteName string) (string
 }
 
 func makeRepositoryURL(url string, ssh bool, token string) string {
+	scheme := "https"
+	path := url
+	if index := strings.Index(url, "://"); index >= 0 {
+		if url[:index] == "http" {
+			scheme = "http"
+		}
+		path = url[index+3:]
+	}
+
 	if ssh {
-		return strings.ReplaceAll(url, "git://github.com/", "git@github.com:")
+		return "git@" + strings.Replace(path, "/", ":", 1)
 	}
 
-	prefix := "https://"
+	prefix := scheme + "://"
 	if len(token) > 0 {
 		prefix += token + "@"
 	}
 
-	return strings.ReplaceAll(url, "git://", prefix)
+	return prefix + path
 }
 
-func configureGit(gitConfig conf.Git) (string, error) {
-	output, err := git.Config(config.Entry("rebase.autoSquash", "true"))
+func configureGit(ctx context.Context, gitConfig conf.Git, ws *Workspace) (string, error) {
+	output, err := git.Config(config.Entry("rebase.autoSquash", "true"), ws.executor(ctx))
 	if err != nil {
 		return output, err
 	}
 
-	output, err = git.Config(config.Entry("push.default", "current"))
+	output, err = git.Config(config.Entry("push.default", "current"), ws.executor(ctx))
 	if err != nil {
 		return output, err
 	}
 
-	return configureGitUserInfo(gitConfig.UserName, gitConfig.Email)
+	return configureGitUserInfo(ctx, gitConfig.UserName, gitConfig.Email, ws)
 }
 
-func configureGitUserInfo(gitUserName, gitUserEmail string) (string, error) {
+func configureGitUserInfo(ctx context.Context, gitUserName, gitUserEmail string, ws *Workspace) (string, error) {
 	if len(gitUserEmail) != 0 {
-		output, err := git.Config(config.Entry("user.email", gitUserEmail))
+		output, err := git.Config(config.Entry("user.email", gitUserEmail), ws.executor(ctx))
 		if err != nil {
 			return output, err
 		}
 	}
 
 	if len(gitUserName) != 0 {
-		output, err := git.Config(config.Entry("user.name", gitUserName))
+		output, err := git.Config(config.Entry("user.name", gitUserName), ws.executor(ctx))
 		if err != nil {
 			return output, err
 		}
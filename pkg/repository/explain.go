@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+// Gate the evaluation of a condition of the process.
+type Gate struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Explanation the evaluation of a pull request, without any change.
+type Explanation struct {
+	PullRequest *github.PullRequest
+	Gates       []Gate
+	// Decision the action of the bot for the pull request.
+	Decision string
+}
+
+// Explain evaluates the gates of the process of a pull request, and the resulting decision.
+// All the gates are evaluated, the decision is the one of the process: the first blocking gate wins.
+// Nothing is changed: no label, no comment, no update, no merge.
+func (r Repository) Explain(ctx context.Context, prNumber int) (*Explanation, error) {
+	pr, err := r.forge.GetPullRequest(ctx, r.owner, r.name, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	exp := &Explanation{PullRequest: pr}
+
+	decide := func(decision string) {
+		if exp.Decision == "" {
+			exp.Decision = decision
+		}
+	}
+
+	// labels
+	labelsGate := r.explainLabels(pr)
+	exp.Gates = append(exp.Gates, labelsGate)
+	if !labelsGate.Passed {
+		decide("ignored: " + labelsGate.Detail)
+	}
+
+	// milestone
+	milestoneGate := Gate{Name: "milestone", Passed: true, Detail: "not required"}
+	if r.config.GetNeedMilestone() {
+		if pr.Milestone == nil {
+			milestoneGate = Gate{Name: "milestone", Detail: "the milestone is missing"}
+			decide("needs a human: the milestone is missing")
+		} else {
+			milestoneGate.Detail = pr.Milestone.GetTitle()
+		}
+	}
+	exp.Gates = append(exp.Gates, milestoneGate)
+
+	// reviews
+	reviewsGate := Gate{Name: "reviews", Passed: true, Detail: fmt.Sprintf("%d approval(s) required", r.getMinReview(pr))}
+	if err = r.hasReviewsApprove(ctx, pr); err != nil {
+		reviewsGate = Gate{Name: "reviews", Detail: err.Error()}
+		decide("needs a human: error related to reviews: " + err.Error())
+	}
+	exp.Gates = append(exp.Gates, reviewsGate)
+
+	// checks
+	checksGate := Gate{Name: "checks", Passed: true, Detail: Success}
+	status, err := r.getAggregatedState(ctx, pr.Head.GetSHA())
+	switch {
+	case err != nil:
+		checksGate = Gate{Name: "checks", Detail: err.Error()}
+		decide(r.explainRetry(pr, r.retry.OnStatuses, "checks status: "+err.Error()))
+	case status == Pending:
+		checksGate = Gate{Name: "checks", Detail: Pending}
+		decide("waiting for the CI")
+	}
+	exp.Gates = append(exp.Gates, checksGate)
+
+	if pr.GetMerged() {
+		exp.Gates = append(exp.Gates, Gate{Name: "merged", Detail: "the pull request is already merged"})
+		decide("nothing: the pull request is already merged")
+
+		return exp, nil
+	}
+
+	// mergeability
+	mergeableGate := Gate{Name: "mergeability", Passed: true, Detail: "mergeable"}
+	if !pr.GetMergeable() {
+		mergeableGate = Gate{Name: "mergeability", Detail: "conflicts must be resolved in the PR"}
+		if pr.Mergeable == nil {
+			mergeableGate.Detail = "the mergeability is not computed yet"
+		}
+
+		decide(r.explainRetry(pr, r.retry.OnMergeable, "conflicts must be resolved in the PR"))
+	}
+	exp.Gates = append(exp.Gates, mergeableGate)
+
+	if r.config.GetMergeQueue() {
+		return r.explainQueue(ctx, exp, pr, decide)
+	}
+
+	// merge method
+	mergeMethod, err := r.getMergeMethod(pr)
+	if err != nil {
+		exp.Gates = append(exp.Gates, Gate{Name: "merge method", Detail: err.Error()})
+		decide("needs a human: " + err.Error())
+
+		return exp, nil
+	}
+	exp.Gates = append(exp.Gates, Gate{Name: "merge method", Passed: true, Detail: mergeMethod})
+
+	// up-to-date
+	needUpdate := r.config.GetForceNeedUpToDate()
+	if r.config.GetCheckNeedUpToDate() {
+		needUpdate, err = r.forge.NeedUpToDate(ctx, r.owner, r.name, pr.Base.GetRef())
+		if err != nil {
+			return nil, fmt.Errorf("unable to get status checks: %w", err)
+		}
+	}
+
+	upToDate, err := r.isUpToDateBranch(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	upToDateGate := Gate{Name: "up-to-date", Passed: true, Detail: "the branch is up-to-date"}
+	if !upToDate {
+		upToDateGate.Passed = !needUpdate && mergeMethod != conf.MergeMethodFastForward
+		upToDateGate.Detail = "the branch is not up-to-date (update required: " + strconv.FormatBool(needUpdate) + ")"
+	}
+	exp.Gates = append(exp.Gates, upToDateGate)
+
+	// merge window
+	frozen, err := r.mergeFrozen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeWindowGate := Gate{Name: "merge window", Passed: true, Detail: "the merges are allowed"}
+	if frozen != "" {
+		mergeWindowGate = Gate{Name: "merge window", Detail: frozen}
+	}
+	exp.Gates = append(exp.Gates, mergeWindowGate)
+
+	switch {
+	case !upToDate && mergeMethod == conf.MergeMethodFastForward:
+		decide(fmt.Sprintf("needs a human: the use of the merge method [%s] is impossible when a branch is not up-to-date", mergeMethod))
+	case !upToDate && needUpdate && (frozen == "" || r.config.GetUpdateWhenFrozen()):
+		decide("update the branch")
+	case frozen != "":
+		decide("waiting for the end of the freeze: " + frozen)
+	default:
+		decide("merge with the method " + mergeMethod)
+	}
+
+	return exp, nil
+}
+
+// explainQueue explains the decision of the merge queue:
+// the batches always use merge commits, and the integration branch replaces the update of the branch of the pull request.
+func (r Repository) explainQueue(ctx context.Context, exp *Explanation, pr *github.PullRequest, decide func(string)) (*Explanation, error) {
+	frozen, err := r.mergeFrozen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeWindowGate := Gate{Name: "merge window", Passed: true, Detail: "the merges are allowed"}
+	if frozen != "" {
+		mergeWindowGate = Gate{Name: "merge window", Detail: frozen}
+	}
+	exp.Gates = append(exp.Gates, mergeWindowGate)
+
+	branch := QueueBranchPrefix + pr.Base.GetRef()
+
+	exp.Gates = append(exp.Gates, Gate{Name: "merge queue", Passed: true, Detail: branch})
+
+	switch {
+	case frozen != "":
+		decide("waiting for the end of the freeze: " + frozen)
+	case hasLabel(pr, r.markers.MergeQueue):
+		decide(fmt.Sprintf("in the batch of %s: waiting for the checks of the integration branch", branch))
+	default:
+		decide("queued in " + branch)
+	}
+
+	return exp, nil
+}
+
+// explainLabels checks the labels used by the search of the pull requests.
+func (r Repository) explainLabels(pr *github.PullRequest) Gate {
+	var blocking []string
+	if !hasLabel(pr, r.markers.NeedMerge) {
+		blocking = append(blocking, "blocking label "+r.markers.NeedMerge)
+	}
+
+	for _, label := range []string{r.markers.NoMerge, r.markers.NeedHumanMerge} {
+		if hasLabel(pr, label) {
+			blocking = append(blocking, "label "+label)
+		}
+	}
+
+	if len(blocking) > 0 {
+		return Gate{Name: "labels", Detail: strings.Join(blocking, ", ")}
+	}
+
+	return Gate{Name: "labels", Passed: true, Detail: r.markers.NeedMerge}
+}
+
+// explainRetry explains the decision of manageRetryLabel.
+func (r Repository) explainRetry(pr *github.PullRequest, retry bool, reason string) string {
+	if !retry || r.retry.Number <= 0 {
+		return "needs a human: " + reason
+	}
+
+	currentRetryLabel := findLabelNameWithPrefix(pr.Labels, r.markers.MergeRetryPrefix)
+	if currentRetryLabel == "" {
+		return fmt.Sprintf("retry [1/%d]: %s", r.retry.Number, reason)
+	}
+
+	number := extractRetryNumber(currentRetryLabel, r.markers.MergeRetryPrefix)
+	if number >= r.retry.Number {
+		return fmt.Sprintf("needs a human: too many retry [%d/%d]: %s", number, r.retry.Number, reason)
+	}
+
+	return fmt.Sprintf("retry [%d/%d]: %s", number+1, r.retry.Number, reason)
+}
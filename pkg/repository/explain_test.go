@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+)
+
+// explainForge a read-only forge: the other methods panic.
+type explainForge struct {
+	forge.Forge
+
+	pr       *github.PullRequest
+	reviews  []*github.PullRequestReview
+	state    string
+	behindBy int
+	// freeze the content of the freeze file, no freeze file if empty.
+	freeze string
+}
+
+func (f explainForge) GetPullRequest(_ context.Context, _, _ string, _ int) (*github.PullRequest, error) {
+	return f.pr, nil
+}
+
+func (f explainForge) ListReviews(_ context.Context, _, _ string, _ int) ([]*github.PullRequestReview, error) {
+	return f.reviews, nil
+}
+
+func (f explainForge) GetCommitState(_ context.Context, _, _, _ string) (string, error) {
+	return f.state, nil
+}
+
+func (f explainForge) GetBehindBy(_ context.Context, _, _ string, _ *github.PullRequest) (int, error) {
+	return f.behindBy, nil
+}
+
+func (f explainForge) NeedUpToDate(_ context.Context, _, _, _ string) (bool, error) {
+	return true, nil
+}
+
+func (f explainForge) GetFileContent(_ context.Context, _, _, _ string) ([]byte, error) {
+	if f.freeze == "" {
+		return nil, forge.ErrNotFound
+	}
+
+	return []byte(f.freeze), nil
+}
+
+func TestRepository_Explain(t *testing.T) {
+	approved := []*github.PullRequestReview{
+		{User: &github.User{Login: github.String("bar")}, State: github.String(Approved)},
+	}
+
+	testCases := []struct {
+		desc             string
+		labels           []string
+		milestone        bool
+		mergeable        *bool
+		reviews          []*github.PullRequestReview
+		state            string
+		behindBy         int
+		freeze           string
+		retry            conf.Retry
+		mergeQueue       bool
+		expectedDecision string
+		expectedFailed   []string
+	}{
+		{
+			desc:             "merge",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			expectedDecision: "merge with the method squash",
+		},
+		{
+			desc:             "merge method from label",
+			labels:           []string{"bot/merge", "bot/merge-method-rebase"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			expectedDecision: "merge with the method rebase",
+		},
+		{
+			desc:             "update the branch",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			behindBy:         2,
+			expectedDecision: "update the branch",
+			expectedFailed:   []string{"up-to-date"},
+		},
+		{
+			desc:             "no merge label",
+			labels:           []string{"bot/merge", "bot/no-merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			expectedDecision: "ignored: label bot/no-merge",
+			expectedFailed:   []string{"labels"},
+		},
+		{
+			desc:             "all the gates are evaluated",
+			labels:           []string{"bot/merge"},
+			mergeable:        github.Bool(false),
+			state:            Pending,
+			expectedDecision: "needs a human: the milestone is missing",
+			expectedFailed:   []string{"milestone", "reviews", "checks", "mergeability"},
+		},
+		{
+			desc:             "waiting for the CI",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Pending,
+			expectedDecision: "waiting for the CI",
+			expectedFailed:   []string{"checks"},
+		},
+		{
+			desc:             "retry on mergeable",
+			labels:           []string{"bot/merge", "bot/merge-retry-1"},
+			milestone:        true,
+			reviews:          approved,
+			state:            Success,
+			retry:            conf.Retry{Number: 3, OnMergeable: true},
+			expectedDecision: "retry [2/3]: conflicts must be resolved in the PR",
+			expectedFailed:   []string{"mergeability"},
+		},
+		{
+			desc:             "merges frozen",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			behindBy:         2,
+			freeze:           "release v2\n",
+			expectedDecision: "waiting for the end of the freeze: .github/lobicornis.freeze: release v2",
+			expectedFailed:   []string{"up-to-date", "merge window"},
+		},
+		{
+			desc:             "merge queue",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			behindBy:         2,
+			mergeQueue:       true,
+			expectedDecision: "queued in lobicornis-queue/master",
+		},
+		{
+			desc:             "merge queue: in the batch",
+			labels:           []string{"bot/merge", "bot/merge-queue"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			mergeQueue:       true,
+			expectedDecision: "in the batch of lobicornis-queue/master: waiting for the checks of the integration branch",
+		},
+		{
+			desc:             "merge queue: merges frozen",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Success,
+			freeze:           "release v2\n",
+			mergeQueue:       true,
+			expectedDecision: "waiting for the end of the freeze: .github/lobicornis.freeze: release v2",
+			expectedFailed:   []string{"merge window"},
+		},
+		{
+			desc:             "merge queue: not ready",
+			labels:           []string{"bot/merge"},
+			milestone:        true,
+			mergeable:        github.Bool(true),
+			reviews:          approved,
+			state:            Pending,
+			mergeQueue:       true,
+			expectedDecision: "waiting for the CI",
+			expectedFailed:   []string{"checks"},
+		},
+	}
+
+	for i, test := range testCases {
+		i, test := i, test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			pr := makePullRequestWithLabels(test.labels, i)
+			pr.Title = github.String("Fix the bug")
+			pr.Mergeable = test.mergeable
+			pr.Head = &github.PullRequestBranch{SHA: github.String("aaa")}
+			pr.Base = &github.PullRequestBranch{Ref: github.String("master")}
+			if test.milestone {
+				pr.Milestone = &github.Milestone{Title: github.String("v1.0")}
+			}
+
+			frg := explainForge{pr: pr, reviews: test.reviews, state: test.state, behindBy: test.behindBy, freeze: test.freeze}
+
+			markers := conf.Markers{
+				NeedMerge:         "bot/merge",
+				NoMerge:           "bot/no-merge",
+				NeedHumanMerge:    "bot/need-human-merge",
+				MergeMethodPrefix: "bot/merge-method-",
+				MergeRetryPrefix:  "bot/merge-retry-",
+				MergeQueue:        "bot/merge-queue",
+			}
+
+			config := conf.RepoConfig{
+				MergeMethod:       conf.String(conf.MergeMethodSquash),
+				MinReview:         conf.Int(1),
+				NeedMilestone:     conf.Bool(true),
+				CheckNeedUpToDate: conf.Bool(true),
+				MergeQueue:        conf.Bool(test.mergeQueue),
+			}
+
+			repo := New(frg, "foo/bar", "", markers, test.retry, conf.Git{}, config, conf.Extra{DryRun: true}, nil, nil, nil)
+
+			exp, err := repo.Explain(context.Background(), i)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedDecision, exp.Decision)
+
+			var failed []string
+			for _, gate := range exp.Gates {
+				if !gate.Passed {
+					failed = append(failed, gate.Name)
+				}
+			}
+
+			assert.Equal(t, test.expectedFailed, failed)
+		})
+	}
+}
@@ -9,11 +9,12 @@ import (
 
 	"github.com/google/go-github/v32/github"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
 )
 
 // Mjolnir the hammer of Thor.
 type Mjolnir struct {
-	client *github.Client
+	forge forge.Forge
 
 	globalFixesIssueRE *regexp.Regexp
 	fixesIssueRE       *regexp.Regexp
@@ -25,9 +26,9 @@ type Mjolnir struct {
 	name  string
 }
 
-func newMjolnir(client *github.Client, owner, name string, dryRun bool) Mjolnir {
+func newMjolnir(frg forge.Forge, owner, name string, dryRun bool) Mjolnir {
 	return Mjolnir{
-		client: client,
+		forge: frg,
 
 		globalFixesIssueRE: regexp.MustCompile(`(?i)(?:close|closes|closed|fix|fixes|fixed|resolve|resolves|resolved)((?:[\s]+#[\d]+)(?:[\s,]+#[\d]+)*(?:[\n\r\s,]|$))`),
 		fixesIssueRE:       regexp.MustCompile(`[\s,]+#`),
@@ -78,27 +79,11 @@ func (m Mjolnir) CloseRelatedIssues(ctx context.Context, pr *github.PullRequest)
 }
 
 func (m Mjolnir) closeIssue(ctx context.Context, pr *github.PullRequest, issueNumber int) error {
-	var milestone *int
-	if pr.Milestone != nil {
-		milestone = pr.Milestone.Number
-	}
-
-	issueRequest := &github.IssueRequest{
-		Milestone: milestone,
-		State:     github.String("closed"),
-	}
-
-	_, _, err := m.client.Issues.Edit(ctx, m.owner, m.name, issueNumber, issueRequest)
-	return err
+	return m.forge.CloseIssue(ctx, m.owner, m.name, issueNumber, pr.Milestone)
 }
 
 func (m Mjolnir) addComment(ctx context.Context, issueNumber int, message string) error {
-	issueComment := &github.IssueComment{
-		Body: github.String(message),
-	}
-
-	_, _, err := m.client.Issues.CreateComment(ctx, m.owner, m.name, issueNumber, issueComment)
-	return err
+	return m.forge.CreateIssueComment(ctx, m.owner, m.name, issueNumber, message)
 }
 
 func (m Mjolnir) parseIssueFixes(ctx context.Context, text string) []int {
@@ -4,11 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v32/github"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
 	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
+	"github.com/traefik/lobicornis/v2/pkg/notify"
+	"github.com/traefik/lobicornis/v2/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const mainBranch = "master"
@@ -19,7 +27,7 @@ type numbered interface {
 
 // Repository a new repository manager.
 type Repository struct {
-	client *github.Client
+	forge forge.Forge
 
 	clone   Clone
 	mjolnir Mjolnir
@@ -36,94 +44,143 @@ type Repository struct {
 	token string
 
 	config conf.RepoConfig
+	freeze *freezeFile
+
+	auditLog *audit.Log
+	notifier *notify.Notifier
 }
 
 // New creates a new repository manager.
-func New(client *github.Client, fullName, token string, markers conf.Markers, retry conf.Retry, gitConfig conf.Git, config conf.RepoConfig, extra conf.Extra) *Repository {
-	repoFragments := strings.Split(fullName, "/")
+func New(frg forge.Forge, fullName, token string, markers conf.Markers, retry conf.Retry, gitConfig conf.Git, config conf.RepoConfig, extra conf.Extra, cache *Cache, auditLog *audit.Log, notifier *notify.Notifier) *Repository {
+	// the owner can contain some slashes (GitLab subgroups).
+	index := strings.LastIndex(fullName, "/")
 
-	owner := repoFragments[0]
-	repoName := repoFragments[1]
+	owner := fullName[:index]
+	repoName := fullName[index+1:]
 
 	return &Repository{
-		client:  client,
-		clone:   newClone(gitConfig, token),
-		mjolnir: newMjolnir(client, owner, repoName, extra.DryRun),
-		dryRun:  extra.DryRun,
-		markers: markers,
-		retry:   retry,
-		owner:   owner,
-		name:    repoName,
-		token:   token,
-		config:  config,
+		forge:    frg,
+		clone:    newClone(gitConfig, token, cache),
+		mjolnir:  newMjolnir(frg, owner, repoName, extra.DryRun),
+		dryRun:   extra.DryRun,
+		markers:  markers,
+		retry:    retry,
+		owner:    owner,
+		name:     repoName,
+		token:    token,
+		config:   config,
+		freeze:   &freezeFile{},
+		auditLog: auditLog,
+		notifier: notifier,
 	}
 }
 
 // Process try to merge a pull request.
 func (r Repository) Process(ctx context.Context, prNumber int) error {
-	pr, _, err := r.client.PullRequests.Get(ctx, r.owner, r.name, prNumber)
+	ctx, span := tracing.Start(ctx, "Repository.Process", attribute.String("repo", r.fullName()), attribute.Int("pr", prNumber))
+
+	err := r.processPullRequest(ctx, prNumber)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r Repository) processPullRequest(ctx context.Context, prNumber int) error {
+	pr, err := r.forge.GetPullRequest(ctx, r.owner, r.name, prNumber)
 	if err != nil {
 		return fmt.Errorf("failed to get pull request: %w", err)
 	}
 
+	rec := r.newRecord(pr)
+
+	ctx = audit.NewContext(ctx, rec)
+
 	err = r.process(ctx, pr)
 	if err != nil {
-		r.callHuman(ctx, pr, err.Error())
+		rec.Decision = audit.DecisionEscalate
+		rec.Error = r.redact(err.Error())
 
-		return err
+		r.callHuman(ctx, pr, err)
 	}
 
-	return nil
+	r.writeRecord(ctx, rec)
+
+	return err
+}
+
+// newRecord creates the audit record of a pull request.
+func (r Repository) newRecord(pr *github.PullRequest) *audit.Record {
+	return &audit.Record{
+		Time:    time.Now(),
+		Repo:    r.fullName(),
+		PR:      pr.GetNumber(),
+		HeadSHA: pr.Head.GetSHA(),
+		DryRun:  r.dryRun,
+	}
+}
+
+// writeRecord appends a record to the audit log.
+func (r Repository) writeRecord(ctx context.Context, rec *audit.Record) {
+	err := r.auditLog.Write(rec)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("unable to write the audit log")
+	}
 }
 
 // process try to merge a pull request.
 func (r Repository) process(ctx context.Context, pr *github.PullRequest) error {
 	logger := log.Ctx(ctx)
+	rec := audit.FromContext(ctx)
+
+	if r.config.GetNeedMilestone() {
+		rec.AddGate("milestone", pr.Milestone != nil, pr.Milestone.GetTitle())
 
-	if r.config.GetNeedMilestone() && pr.Milestone == nil {
-		return errors.New("the milestone is missing")
+		if pr.Milestone == nil {
+			return withReason(reasonMilestone, errors.New("the milestone is missing"))
+		}
 	}
 
 	err := r.hasReviewsApprove(ctx, pr)
 	if err != nil {
-		return fmt.Errorf("error related to reviews: %w", err)
+		rec.AddGate("reviews", false, err.Error())
+		return withReason(reasonReviews, fmt.Errorf("error related to reviews: %w", err))
 	}
 
-	status, err := r.getAggregatedState(ctx, pr)
+	rec.AddGate("reviews", true, fmt.Sprintf("%d approval(s) required", r.getMinReview(pr)))
+
+	status, err := r.getAggregatedState(ctx, pr.Head.GetSHA())
 	if err != nil {
 		logger.Error().Err(err).Msg("Checks status")
+		rec.AddGate("checks", false, err.Error())
 
-		return r.manageRetryLabel(ctx, pr, r.retry.OnStatuses, fmt.Errorf("checks status: %w", err))
+		return withReason(reasonChecks, r.manageRetryLabel(ctx, pr, r.retry.OnStatuses, fmt.Errorf("checks status: %w", err)))
 	}
 
+	rec.AddGate("checks", status == Success, status)
+
 	if status == Pending {
 		// skip
 		logger.Info().Msg("State: pending. Waiting for the CI.")
+		rec.Decision = audit.DecisionWait
 		return nil
 	}
 
 	if pr.GetMerged() {
 		logger.Info().Msg("the PR is already merged")
+		rec.Decision = audit.DecisionNone
 
-		labelsToRemove := []string{
-			r.markers.MergeInProgress,
-			r.markers.NeedMerge,
-			r.markers.LightReview,
-			r.markers.MergeMethodPrefix + conf.MergeMethodSquash,
-			r.markers.MergeMethodPrefix + conf.MergeMethodMerge,
-			r.markers.MergeMethodPrefix + conf.MergeMethodRebase,
-			r.markers.MergeMethodPrefix + conf.MergeMethodFastForward,
-		}
-		err = r.removeLabels(ctx, pr, labelsToRemove)
+		err = r.removeLabels(ctx, pr, r.mergedLabels())
 		ignoreError(ctx, err)
 
 		return nil
 	}
 
+	rec.AddGate("mergeability", pr.GetMergeable(), "")
+
 	if !pr.GetMergeable() {
 		logger.Info().Msg("Conflicts must be resolved in the PR.")
 
-		return r.manageRetryLabel(ctx, pr, r.retry.OnMergeable, errors.New("conflicts must be resolved in the PR"))
+		return withReason(reasonConflicts, r.manageRetryLabel(ctx, pr, r.retry.OnMergeable, errors.New("conflicts must be resolved in the PR")))
 	}
 
 	r.cleanRetryLabel(ctx, pr)
@@ -131,28 +188,46 @@ func (r Repository) process(ctx context.Context, pr *github.PullRequest) error {
 	// Get status checks
 	var needUpdate bool
 	if r.config.GetCheckNeedUpToDate() {
-		rcs, _, errCheck := r.client.Repositories.GetRequiredStatusChecks(ctx, r.owner, r.name, pr.Base.GetRef())
-		if errCheck != nil {
-			return fmt.Errorf("unable to get status checks: %w", errCheck)
+		needUpdate, err = r.forge.NeedUpToDate(ctx, r.owner, r.name, pr.Base.GetRef())
+		if err != nil {
+			return fmt.Errorf("unable to get status checks: %w", err)
 		}
-
-		needUpdate = rcs.Strict
 	} else if r.config.GetForceNeedUpToDate() {
 		needUpdate = true
 	}
 
 	mergeMethod, err := r.getMergeMethod(pr)
 	if err != nil {
-		return err
+		rec.AddGate("merge method", false, err.Error())
+		return withReason(reasonMergeMethod, err)
 	}
 
+	rec.AddGate("merge method", true, mergeMethod)
+	rec.MergeMethod = mergeMethod
+
 	upToDateBranch, err := r.isUpToDateBranch(ctx, pr)
 	if err != nil {
 		return err
 	}
 
+	rec.AddGate("up-to-date", upToDateBranch, "update required: "+strconv.FormatBool(needUpdate))
+
 	if !upToDateBranch && mergeMethod == conf.MergeMethodFastForward {
-		return fmt.Errorf("the use of the merge method [%s] is impossible when a branch is not up-to-date", mergeMethod)
+		return withReason(reasonMergeMethod, fmt.Errorf("the use of the merge method [%s] is impossible when a branch is not up-to-date", mergeMethod))
+	}
+
+	frozen, err := r.mergeFrozen(ctx)
+	if err != nil {
+		return err
+	}
+
+	rec.AddGate("merge window", frozen == "", frozen)
+
+	// the branch can still be updated when the merges are frozen.
+	if frozen != "" && (!needUpdate || upToDateBranch || !r.config.GetUpdateWhenFrozen()) {
+		logger.Info().Msgf("The merges are frozen: %s", frozen)
+		rec.Decision = audit.DecisionWait
+		return nil
 	}
 
 	// Need to be up to date?
@@ -165,8 +240,10 @@ func (r Repository) process(ctx context.Context, pr *github.PullRequest) error {
 		} else {
 			err := r.update(ctx, pr)
 			if err != nil {
-				return fmt.Errorf("failed to update: %w", err)
+				return withReason(reasonUpdate, fmt.Errorf("failed to update: %w", err))
 			}
+
+			rec.Decision = audit.DecisionUpdate
 		}
 	} else {
 		err := r.merge(ctx, pr, mergeMethod)
@@ -178,8 +255,15 @@ func (r Repository) process(ctx context.Context, pr *github.PullRequest) error {
 	return nil
 }
 
-func (r Repository) callHuman(ctx context.Context, pr *github.PullRequest, message string) {
-	err := r.addComment(ctx, pr, ":no_entry_sign: "+message)
+// callHuman escalates a pull request to a human (markers.needHumanMerge).
+func (r Repository) callHuman(ctx context.Context, pr *github.PullRequest, cause error) {
+	if !r.dryRun {
+		metrics.Escalated.WithLabelValues(r.fullName(), reasonOf(cause)).Inc()
+	}
+
+	r.notify(ctx, conf.EventEscalated, pr, cause.Error())
+
+	err := r.addComment(ctx, pr, ":no_entry_sign: "+cause.Error())
 	ignoreError(ctx, err)
 
 	err = r.addLabels(ctx, pr, r.markers.NeedHumanMerge)
@@ -194,18 +278,31 @@ func (r Repository) addComment(ctx context.Context, pr *github.PullRequest, mess
 		return nil
 	}
 
-	msg := strings.ReplaceAll(message, r.token, "xxx")
+	msg := r.redact(message)
 
 	if r.dryRun {
 		log.Ctx(ctx).Debug().Msgf("Add comment: %s", msg)
+		audit.FromContext(ctx).AddComment(msg)
 		return nil
 	}
 
-	comment := &github.IssueComment{Body: github.String(msg)}
+	err := r.forge.CreateComment(ctx, r.owner, r.name, pr.GetNumber(), msg)
+	if err != nil {
+		return err
+	}
+
+	audit.FromContext(ctx).AddComment(msg)
+
+	return nil
+}
 
-	_, _, err := r.client.Issues.CreateComment(ctx, r.owner, r.name, pr.GetNumber(), comment)
+// redact hides the token in a message.
+func (r Repository) redact(message string) string {
+	if r.token == "" {
+		return message
+	}
 
-	return err
+	return strings.ReplaceAll(message, r.token, "xxx")
 }
 
 func ignoreError(ctx context.Context, err error) {
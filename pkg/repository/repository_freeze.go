@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+)
+
+// freezeFile the state of the freeze file, read once by a repository manager (once per repository and sweep).
+type freezeFile struct {
+	once   sync.Once
+	reason string
+	err    error
+}
+
+// mergeFrozen checks if the merges are frozen: outside the merge windows, during a freeze,
+// or when the freeze file exists on the default branch.
+// Returns the reason of the freeze, or an empty string.
+func (r Repository) mergeFrozen(ctx context.Context) (string, error) {
+	reason, err := r.config.MergeFrozen(time.Now())
+	if err != nil || reason != "" {
+		return reason, err
+	}
+
+	if r.freeze == nil {
+		return r.readFreezeFile(ctx)
+	}
+
+	r.freeze.once.Do(func() {
+		r.freeze.reason, r.freeze.err = r.readFreezeFile(ctx)
+	})
+
+	return r.freeze.reason, r.freeze.err
+}
+
+// readFreezeFile gets the reason of the freeze from the freeze file, or an empty string if the file doesn't exist.
+func (r Repository) readFreezeFile(ctx context.Context) (string, error) {
+	content, err := r.forge.GetFileContent(ctx, r.owner, r.name, conf.FreezeFile)
+	if errors.Is(err, forge.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get %s: %w", conf.FreezeFile, err)
+	}
+
+	reason := conf.FreezeFile
+	if line := strings.TrimSpace(strings.SplitN(string(content), "\n", 2)[0]); line != "" {
+		reason += ": " + line
+	}
+
+	return reason, nil
+}
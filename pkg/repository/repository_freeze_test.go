@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+)
+
+// freezeForge counts the reads of the freeze file.
+type freezeForge struct {
+	forge.Forge
+
+	content string
+	reads   *int32
+}
+
+func (f freezeForge) GetFileContent(_ context.Context, _, _, _ string) ([]byte, error) {
+	atomic.AddInt32(f.reads, 1)
+
+	return []byte(f.content), nil
+}
+
+func TestRepository_mergeFrozen(t *testing.T) {
+	var reads int32
+
+	frg := freezeForge{content: "release in progress\nsee #1", reads: &reads}
+
+	repo := New(frg, "foo/bar", "", conf.Markers{}, conf.Retry{}, conf.Git{}, conf.RepoConfig{}, conf.Extra{}, nil, nil, nil)
+
+	for i := 0; i < 3; i++ {
+		reason, err := repo.mergeFrozen(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, conf.FreezeFile+": release in progress", reason)
+	}
+
+	// the freeze file is read once by a repository manager.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reads))
+}
@@ -2,39 +2,41 @@ package repository
 
 import (
 	"context"
-	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/google/go-github/v32/github"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
 )
 
 // removeLabels remove some labels on an issue (PR).
 func (r *Repository) removeLabels(ctx context.Context, pr numbered, labelsToRemove []string) error {
-	freshIssue, _, err := r.client.Issues.Get(ctx, r.owner, r.name, pr.GetNumber())
+	freshIssue, err := r.forge.GetIssue(ctx, r.owner, r.name, pr.GetNumber())
 	if err != nil {
 		return err
 	}
 
-	var newLabels []string
+	var newLabels, removed []string
 	for _, lbl := range freshIssue.Labels {
-		if !contains(labelsToRemove, lbl.GetName()) {
+		if contains(labelsToRemove, lbl.GetName()) {
+			removed = append(removed, lbl.GetName())
+		} else {
 			newLabels = append(newLabels, lbl.GetName())
 		}
 	}
 
-	if len(freshIssue.Labels) == len(newLabels) {
+	if len(removed) == 0 {
 		return nil
 	}
 
-	if newLabels == nil {
-		// Due to go-github/GitHub API constraint
-		newLabels = []string{}
+	err = r.forge.ReplaceLabels(ctx, r.owner, r.name, pr.GetNumber(), newLabels)
+	if err != nil {
+		return err
 	}
 
-	_, _, err = r.client.Issues.ReplaceLabelsForIssue(ctx, r.owner, r.name, pr.GetNumber(), newLabels)
-	return err
+	audit.FromContext(ctx).RemoveLabels(removed...)
+
+	return nil
 }
 
 // removeLabel remove a label on an issue (PR).
@@ -45,17 +47,16 @@ func (r Repository) removeLabel(ctx context.Context, pr *github.PullRequest, lab
 
 	log.Ctx(ctx).Debug().Msgf("Remove label: %s. Dry run: %v", label, r.dryRun)
 	if r.dryRun {
+		audit.FromContext(ctx).RemoveLabels(label)
 		return nil
 	}
 
-	resp, err := r.client.Issues.RemoveLabelForIssue(ctx, r.owner, r.name, pr.GetNumber(), label)
+	err := r.forge.RemoveLabel(ctx, r.owner, r.name, pr.GetNumber(), label)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to remove label %s. Status code: %d", label, resp.StatusCode)
-	}
+	audit.FromContext(ctx).RemoveLabels(label)
 
 	return nil
 }
@@ -65,17 +66,16 @@ func (r *Repository) addLabels(ctx context.Context, pr numbered, labels ...strin
 	log.Ctx(ctx).Debug().Msgf("Add labels: %s. Dry run: %v", labels, r.dryRun)
 
 	if r.dryRun {
+		audit.FromContext(ctx).AddLabels(labels...)
 		return nil
 	}
 
-	_, resp, err := r.client.Issues.AddLabelsToIssue(ctx, r.owner, r.name, pr.GetNumber(), labels)
+	err := r.forge.AddLabels(ctx, r.owner, r.name, pr.GetNumber(), labels...)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to add labels %v. Status code: %d", labels, resp.StatusCode)
-	}
+	audit.FromContext(ctx).AddLabels(labels...)
 
 	return nil
 }
@@ -5,8 +5,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"regexp"
 	"strings"
 
@@ -15,6 +13,7 @@ import (
 	"github.com/ldez/go-git-cmd-wrapper/merge"
 	"github.com/ldez/go-git-cmd-wrapper/push"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
 	"github.com/traefik/lobicornis/v2/pkg/conf"
 )
 
@@ -28,6 +27,8 @@ const (
 type Result struct {
 	Message string
 	Merged  bool
+	// SHA the SHA of the merge commit.
+	SHA string
 }
 
 func (r Repository) getMergeMethod(pr *github.PullRequest) (string, error) {
@@ -67,8 +68,8 @@ func (r Repository) getMergeMethod(pr *github.PullRequest) (string, error) {
 func (r Repository) merge(ctx context.Context, pr *github.PullRequest, mergeMethod string) error {
 	if !pr.GetMaintainerCanModify() && !isOnMainRepository(pr) && mergeMethod == conf.MergeMethodFastForward {
 		// note: it's not possible to edit a PR from an organization.
-		return fmt.Errorf("the use of the merge method [%s] is impossible when a branch from an organization "+
-			"or if the contributor doesn't allow maintainer modification (GitHub option)", mergeMethod)
+		return withReason(reasonMergeMethod, fmt.Errorf("the use of the merge method [%s] is impossible when a branch from an organization "+
+			"or if the contributor doesn't allow maintainer modification (GitHub option)", mergeMethod))
 	}
 
 	log.Ctx(ctx).Info().Msgf("MERGE(%s)\n", mergeMethod)
@@ -76,6 +77,9 @@ func (r Repository) merge(ctx context.Context, pr *github.PullRequest, mergeMeth
 	err := r.removeLabel(ctx, pr, r.markers.MergeInProgress)
 	ignoreError(ctx, err)
 
+	rec := audit.FromContext(ctx)
+	rec.Decision = audit.DecisionMerge
+
 	if !r.dryRun {
 		var result Result
 		result, err = r.mergePullRequest(ctx, pr, mergeMethod)
@@ -84,9 +88,14 @@ func (r Repository) merge(ctx context.Context, pr *github.PullRequest, mergeMeth
 		log.Ctx(ctx).Info().Msg(result.Message)
 
 		if !result.Merged {
-			return fmt.Errorf("failed to merge PR: %s", result.Message)
+			return withReason(reasonMerge, fmt.Errorf("failed to merge PR: %s", result.Message))
 		}
 
+		rec.MergeSHA = result.SHA
+
+		r.observeMerge(ctx, pr, mergeMethod)
+		r.notify(ctx, conf.EventMerged, pr, mergeMethod)
+
 		labelsToRemove := []string{
 			r.markers.NeedMerge,
 			r.markers.LightReview,
@@ -111,10 +120,10 @@ func (r Repository) mergePullRequest(ctx context.Context, pr *github.PullRequest
 		return r.fastForward(ctx, pr)
 	}
 
-	return r.githubMerge(ctx, pr, mergeMethod)
+	return r.forgeMerge(ctx, pr, mergeMethod)
 }
 
-func (r Repository) githubMerge(ctx context.Context, pr *github.PullRequest, mergeMethod string) (Result, error) {
+func (r Repository) forgeMerge(ctx context.Context, pr *github.PullRequest, mergeMethod string) (Result, error) {
 	if r.dryRun {
 		return Result{Message: "Fake merge: dry run", Merged: true}, nil
 	}
@@ -126,7 +135,7 @@ func (r Repository) githubMerge(ctx context.Context, pr *github.PullRequest, mer
 
 	message := r.getCommitMessage(mergeMethod, pr)
 
-	result, _, err := r.client.PullRequests.Merge(ctx, r.owner, r.name, pr.GetNumber(), message, options)
+	result, err := r.forge.Merge(ctx, r.owner, r.name, pr.GetNumber(), message, options)
 	if err != nil {
 		return Result{Message: err.Error(), Merged: false}, err
 	}
@@ -134,6 +143,7 @@ func (r Repository) githubMerge(ctx context.Context, pr *github.PullRequest, mer
 	return Result{
 		Message: result.GetMessage(),
 		Merged:  result.GetMerged(),
+		SHA:     result.GetSHA(),
 	}, nil
 }
 
@@ -158,24 +168,17 @@ func (r Repository) getCommitMessage(mergeMethod string, pr *github.PullRequest)
 }
 
 func (r Repository) fastForward(ctx context.Context, pr *github.PullRequest) (Result, error) {
-	dir, err := ioutil.TempDir("", "myrmica-lobicornis")
-	if err != nil {
-		return Result{Message: err.Error(), Merged: false}, err
-	}
-
-	defer func() { ignoreError(ctx, os.RemoveAll(dir)) }()
-
-	err = os.Chdir(dir)
+	ws, err := NewWorkspace(r.clone.git.WorkDir)
 	if err != nil {
 		return Result{Message: err.Error(), Merged: false}, err
 	}
 
-	tempDir, _ := os.Getwd()
+	defer func() { ignoreError(ctx, ws.Remove()) }()
 
 	logger := log.Ctx(ctx)
-	logger.Info().Msg(tempDir)
+	logger.Info().Msg(ws.Dir())
 
-	output, err := r.clone.PullRequestForMerge(ctx, pr)
+	output, err := r.clone.PullRequestForMerge(ctx, pr, ws)
 	if err != nil {
 		logger.Error().Err(err).Msg(output)
 		return Result{Message: err.Error(), Merged: false}, err
@@ -188,7 +191,7 @@ func (r Repository) fastForward(ctx context.Context, pr *github.PullRequest) (Re
 
 	ref := fmt.Sprintf("%s/%s", remoteName, pr.Head.GetRef())
 
-	output, err = git.Merge(merge.FfOnly, merge.Commits(ref), git.Debugger(r.debug))
+	output, err = git.Merge(merge.FfOnly, merge.Commits(ref), git.Debugger(r.debug), ws.executor(ctx))
 	if err != nil {
 		logger.Error().Err(err).Msg(output)
 		return Result{Message: err.Error(), Merged: false}, err
@@ -198,13 +201,14 @@ func (r Repository) fastForward(ctx context.Context, pr *github.PullRequest) (Re
 		git.Cond(r.dryRun, push.DryRun),
 		push.Remote(RemoteOrigin),
 		push.RefSpec(pr.Base.GetRef()),
-		git.Debugger(r.debug))
+		git.Debugger(r.debug),
+		ws.executor(ctx))
 	if err != nil {
 		logger.Error().Err(err).Msg(output)
 		return Result{Message: err.Error(), Merged: false}, err
 	}
 
-	return Result{Merged: true, Message: "Merged"}, nil
+	return Result{Merged: true, Message: "Merged", SHA: pr.Head.GetSHA()}, nil
 }
 
 // getCoAuthors Extracts co-author from PR description.
@@ -146,7 +146,7 @@ func TestRepository_getMergeMethod(t *testing.T) {
 	}
 
 	for i, test := range testCases {
-		test := test
+		i, test := i, test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
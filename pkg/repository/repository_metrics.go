@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
+)
+
+// The reasons of the escalations to a human, and of the merges (merge method, or queue).
+const (
+	reasonMilestone   = "milestone"
+	reasonReviews     = "reviews"
+	reasonChecks      = "checks"
+	reasonConflicts   = "conflicts"
+	reasonMergeMethod = "merge_method"
+	reasonUpdate      = "update"
+	reasonMerge       = "merge"
+	reasonQueue       = "queue"
+	reasonUnknown     = "error"
+)
+
+// reasonError an error with the reason of the escalation to a human.
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func withReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &reasonError{reason: reason, err: err}
+}
+
+func (e *reasonError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reasonError) Unwrap() error {
+	return e.err
+}
+
+// reasonOf gets the reason of the escalation of an error.
+func reasonOf(err error) string {
+	var re *reasonError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+
+	return reasonUnknown
+}
+
+func (r Repository) fullName() string {
+	return r.owner + "/" + r.name
+}
+
+// observeMerge records the merge of a pull request, and the time since the addition of the label markers.needMerge.
+func (r Repository) observeMerge(ctx context.Context, pr *github.PullRequest, reason string) {
+	metrics.Merged.WithLabelValues(r.fullName(), reason).Inc()
+
+	labeledAt, err := r.forge.GetLabeledAt(ctx, r.owner, r.name, pr.GetNumber(), r.markers.NeedMerge)
+	if err != nil {
+		ignoreError(ctx, err)
+		return
+	}
+
+	metrics.TimeToMerge.Observe(time.Since(labeledAt).Seconds())
+}
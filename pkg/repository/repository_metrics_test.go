@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reasonOf(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		err      error
+		expected string
+	}{
+		{
+			desc:     "without reason",
+			err:      errors.New("boom"),
+			expected: reasonUnknown,
+		},
+		{
+			desc:     "with reason",
+			err:      withReason(reasonReviews, errors.New("need more review [0/1]")),
+			expected: reasonReviews,
+		},
+		{
+			desc:     "wrapped",
+			err:      fmt.Errorf("too many retry [3/3]: %w", withReason(reasonConflicts, errors.New("conflicts"))),
+			expected: reasonConflicts,
+		},
+		{
+			desc:     "the outermost reason",
+			err:      withReason(reasonUpdate, fmt.Errorf("failed to update: %w", withReason(reasonMerge, errors.New("boom")))),
+			expected: reasonUpdate,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, reasonOf(test.err))
+		})
+	}
+}
+
+func Test_withReason(t *testing.T) {
+	assert.NoError(t, withReason(reasonChecks, nil))
+
+	err := withReason(reasonChecks, errors.New("checks status: failure"))
+	assert.EqualError(t, err, "checks status: failure")
+}
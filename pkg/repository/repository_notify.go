@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/notify"
+)
+
+// notify sends an event of a pull request to the notifiers of the repository (notify).
+// Nothing is sent in dry run mode.
+func (r Repository) notify(ctx context.Context, eventType string, pr *github.PullRequest, detail string) {
+	if r.dryRun || len(r.config.Notify) == 0 {
+		return
+	}
+
+	event := notify.Event{
+		Type:     eventType,
+		Time:     time.Now(),
+		Repo:     r.fullName(),
+		PR:       pr.GetNumber(),
+		Title:    pr.GetTitle(),
+		URL:      pr.GetHTMLURL(),
+		Author:   pr.GetUser().GetLogin(),
+		Mentions: r.config.Mentions,
+		Detail:   r.redact(detail),
+	}
+
+	err := r.notifier.Notify(ctx, r.config.Notify, event)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("unable to send the notifications")
+	}
+}
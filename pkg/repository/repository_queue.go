@@ -0,0 +1,719 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/ldez/go-git-cmd-wrapper/checkout"
+	"github.com/ldez/go-git-cmd-wrapper/commit"
+	"github.com/ldez/go-git-cmd-wrapper/fetch"
+	"github.com/ldez/go-git-cmd-wrapper/git"
+	"github.com/ldez/go-git-cmd-wrapper/merge"
+	"github.com/ldez/go-git-cmd-wrapper/push"
+	"github.com/ldez/go-git-cmd-wrapper/revparse"
+	"github.com/ldez/go-git-cmd-wrapper/types"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+	"github.com/traefik/lobicornis/v2/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// QueueBranchPrefix the prefix of the integration branches of the merge queue.
+const QueueBranchPrefix = "lobicornis-queue/"
+
+// batchTrailer identifies a pull request of the batch in the message of the head of an integration branch.
+var batchTrailer = regexp.MustCompile(`(?m)^Lobicornis-Batch: #(\d+) ([0-9a-f]{40})$`)
+
+// queueEntry a pull request merged into an integration branch.
+type queueEntry struct {
+	number int
+	sha    string
+}
+
+// ProcessQueue processes the pull requests of a repository as a merge queue.
+// The oldest pull requests are merged together into an integration branch,
+// the base branch is fast-forwarded to the integration branch when its checks pass,
+// and the batch is bisected when its checks fail.
+func (r Repository) ProcessQueue(ctx context.Context, issues []*github.Issue) error {
+	ctx, span := tracing.Start(ctx, "Repository.ProcessQueue", attribute.String("repo", r.fullName()))
+
+	err := r.processQueue(ctx, issues)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r Repository) processQueue(ctx context.Context, issues []*github.Issue) error {
+	// the batches are kept until the end of the freeze.
+	frozen, err := r.mergeFrozen(ctx)
+	if err != nil {
+		return err
+	}
+
+	if frozen != "" {
+		log.Ctx(ctx).Info().Msgf("The merges are frozen: %s", frozen)
+		return nil
+	}
+
+	var queued []*github.Issue
+	for _, issue := range issues {
+		if hasIssueLabel(issue, r.markers.MergeQueue) {
+			queued = append(queued, issue)
+		}
+	}
+
+	if len(queued) > 0 {
+		return r.checkBatch(ctx, queued)
+	}
+
+	return r.startBatch(ctx, issues)
+}
+
+// startBatch selects the oldest pull requests ready to be merged, and creates a new integration branch.
+func (r Repository) startBatch(ctx context.Context, issues []*github.Issue) error {
+	logger := log.Ctx(ctx)
+
+	var batch []*github.PullRequest
+	var recs []*audit.Record
+	for _, issue := range issues {
+		if len(batch) >= r.config.GetMergeQueueSize() {
+			break
+		}
+
+		pr, err := r.forge.GetPullRequest(ctx, r.owner, r.name, issue.GetNumber())
+		if err != nil {
+			return fmt.Errorf("failed to get pull request: %w", err)
+		}
+
+		// all the pull requests of a batch must have the same base branch.
+		if len(batch) > 0 && pr.Base.GetRef() != batch[0].Base.GetRef() {
+			continue
+		}
+
+		prLogger := logger.With().Int("pr", pr.GetNumber()).Logger()
+
+		rec := r.newRecord(pr)
+		prCtx := audit.NewContext(prLogger.WithContext(ctx), rec)
+
+		ready, err := r.isReadyForQueue(prCtx, issue, pr)
+		if err != nil {
+			prLogger.Error().Err(err).Msg("Failed to queue")
+
+			rec.Decision = audit.DecisionEscalate
+			rec.Error = r.redact(err.Error())
+
+			r.callHuman(prCtx, pr, err)
+			r.writeRecord(ctx, rec)
+
+			continue
+		}
+
+		if !ready {
+			r.writeRecord(ctx, rec)
+			continue
+		}
+
+		batch = append(batch, pr)
+		recs = append(recs, rec)
+	}
+
+	if len(batch) == 0 {
+		logger.Debug().Msg("Nothing to queue.")
+		return nil
+	}
+
+	return r.queueBatch(ctx, batch, recs)
+}
+
+// isReadyForQueue checks if a pull request can join a batch.
+// The merge method of the pull request is ignored: the batches always use merge commits.
+func (r Repository) isReadyForQueue(ctx context.Context, issue *github.Issue, pr *github.PullRequest) (bool, error) {
+	logger := log.Ctx(ctx)
+	rec := audit.FromContext(ctx)
+
+	if findLabelNameWithPrefix(pr.Labels, r.markers.MergeRetryPrefix) != "" && time.Since(issue.GetUpdatedAt()) < r.retry.Interval {
+		logger.Debug().Msg("Waiting for the next retry.")
+		rec.Decision = audit.DecisionWait
+		return false, nil
+	}
+
+	if r.config.GetNeedMilestone() {
+		rec.AddGate("milestone", pr.Milestone != nil, pr.Milestone.GetTitle())
+
+		if pr.Milestone == nil {
+			return false, withReason(reasonMilestone, errors.New("the milestone is missing"))
+		}
+	}
+
+	err := r.hasReviewsApprove(ctx, pr)
+	if err != nil {
+		rec.AddGate("reviews", false, err.Error())
+		return false, withReason(reasonReviews, fmt.Errorf("error related to reviews: %w", err))
+	}
+
+	rec.AddGate("reviews", true, fmt.Sprintf("%d approval(s) required", r.getMinReview(pr)))
+
+	status, err := r.getAggregatedState(ctx, pr.Head.GetSHA())
+	if err != nil {
+		logger.Error().Err(err).Msg("Checks status")
+		rec.AddGate("checks", false, err.Error())
+
+		return false, withReason(reasonChecks, r.manageRetryLabel(ctx, pr, r.retry.OnStatuses, fmt.Errorf("checks status: %w", err)))
+	}
+
+	rec.AddGate("checks", status == Success, status)
+
+	if status == Pending {
+		logger.Info().Msg("State: pending. Waiting for the CI.")
+		rec.Decision = audit.DecisionWait
+		return false, nil
+	}
+
+	if pr.GetMerged() {
+		logger.Info().Msg("the PR is already merged")
+		rec.Decision = audit.DecisionNone
+
+		err = r.removeLabels(ctx, pr, r.mergedLabels())
+		ignoreError(ctx, err)
+
+		return false, nil
+	}
+
+	rec.AddGate("mergeability", pr.GetMergeable(), "")
+
+	if !pr.GetMergeable() {
+		logger.Info().Msg("Conflicts must be resolved in the PR.")
+
+		return false, withReason(reasonConflicts, r.manageRetryLabel(ctx, pr, r.retry.OnMergeable, errors.New("conflicts must be resolved in the PR")))
+	}
+
+	r.cleanRetryLabel(ctx, pr)
+
+	return true, nil
+}
+
+// queueBatch merges the pull requests into the integration branch, and pushes it.
+// The pull requests that cannot be merged cleanly are removed from the batch.
+// recs are the audit records of the pull requests of the batch (same order).
+func (r Repository) queueBatch(ctx context.Context, batch []*github.PullRequest, recs []*audit.Record) error {
+	logger := log.Ctx(ctx)
+
+	branch := QueueBranchPrefix + batch[0].Base.GetRef()
+
+	queued, err := r.pushIntegrationBranch(ctx, batch, branch)
+	if err != nil {
+		for _, rec := range recs {
+			rec.AddGate("integration branch", false, branch)
+			rec.Decision = audit.DecisionWait
+			rec.Error = r.redact(err.Error())
+
+			r.writeRecord(ctx, rec)
+		}
+
+		return err
+	}
+
+	for i, pr := range batch {
+		rec := recs[i]
+		prCtx := audit.NewContext(ctx, rec)
+
+		if containsPR(queued, pr) {
+			rec.AddGate("integration branch", true, branch)
+			rec.Decision = audit.DecisionQueue
+
+			err = r.addLabels(prCtx, pr, r.markers.MergeQueue)
+			ignoreError(ctx, err)
+		} else {
+			rec.AddGate("integration branch", false, "the pull request has changed or conflicts with the batch")
+			rec.Decision = audit.DecisionWait
+
+			err = r.removeLabel(prCtx, pr, r.markers.MergeQueue)
+			ignoreError(ctx, err)
+		}
+
+		r.writeRecord(ctx, rec)
+	}
+
+	if len(queued) > 0 {
+		logger.Info().Msgf("QUEUE(%s) %v", branch, prNumbers(queued))
+	}
+
+	return nil
+}
+
+func (r Repository) pushIntegrationBranch(ctx context.Context, batch []*github.PullRequest, branch string) ([]*github.PullRequest, error) {
+	logger := log.Ctx(ctx)
+
+	ws, err := NewWorkspace(r.clone.git.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { ignoreError(ctx, ws.Remove()) }()
+
+	output, err := r.clone.BaseBranch(ctx, batch[0], ws)
+	if err != nil {
+		logger.Error().Err(err).Msg(output)
+		return nil, err
+	}
+
+	output, err = git.Checkout(checkout.NewBranchForce(branch), git.Debugger(r.debug), ws.executor(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg(output)
+		return nil, fmt.Errorf("failed to create the integration branch %s: %w", branch, err)
+	}
+
+	var queued []*github.PullRequest
+	for _, pr := range batch {
+		ok, err := r.mergeIntoIntegrationBranch(ctx, pr, ws)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			queued = append(queued, pr)
+		}
+	}
+
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	output, err = git.Commit(commit.Amend, withMessage(batchCommitMessage(queued)), git.Debugger(r.debug), ws.executor(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg(output)
+		return nil, fmt.Errorf("failed to write the batch in the integration branch %s: %w", branch, err)
+	}
+
+	output, err = git.Push(
+		git.Cond(r.dryRun, push.DryRun),
+		push.Force,
+		push.Remote(RemoteOrigin),
+		push.RefSpec(branch),
+		git.Debugger(r.debug),
+		ws.executor(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg(output)
+		return nil, fmt.Errorf("failed to push the integration branch %s: %w", branch, err)
+	}
+
+	return queued, nil
+}
+
+// mergeIntoIntegrationBranch merges the head of a pull request into the integration branch.
+// Returns false if the pull request has changed or conflicts with the other pull requests of the batch.
+func (r Repository) mergeIntoIntegrationBranch(ctx context.Context, pr *github.PullRequest, ws *Workspace) (bool, error) {
+	logger := log.Ctx(ctx).With().Int("pr", pr.GetNumber()).Logger()
+
+	// the head of a pull request is always available on the base repository, even for a fork.
+	// refs/pull/<number>/head is specific to GitHub: the validation of the configuration allows the merge queue only with GitHub.
+	ref := fmt.Sprintf("refs/pull/%d/head", pr.GetNumber())
+
+	output, err := git.Fetch(fetch.NoTags, fetch.Remote(RemoteOrigin), fetch.RefSpec(ref), git.Debugger(r.debug), ws.executor(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg(output)
+		return false, fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+
+	output, err = git.RevParse(revparse.Args("FETCH_HEAD"), git.Debugger(r.debug), ws.executor(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg(output)
+		return false, fmt.Errorf("failed to get the head of %s: %w", ref, err)
+	}
+
+	if strings.TrimSpace(output) != pr.Head.GetSHA() {
+		logger.Info().Msg("The pull request has changed: removed from the batch.")
+		return false, nil
+	}
+
+	output, err = git.Merge(merge.NoFf, merge.M(queueCommitMessage(pr)), merge.Commits(pr.Head.GetSHA()), git.Debugger(r.debug), ws.executor(ctx))
+	if err != nil {
+		logger.Info().Msgf("The pull request conflicts with the batch: removed from the batch.\n%s", output)
+
+		output, err = git.Merge(merge.Abort, git.Debugger(r.debug), ws.executor(ctx))
+		if err != nil {
+			logger.Error().Err(err).Msg(output)
+			return false, fmt.Errorf("failed to abort the merge: %w", err)
+		}
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// checkBatch checks the integration branch of the current batch.
+func (r Repository) checkBatch(ctx context.Context, queued []*github.Issue) error {
+	logger := log.Ctx(ctx)
+
+	var prs []*github.PullRequest
+	for _, issue := range queued {
+		pr, err := r.forge.GetPullRequest(ctx, r.owner, r.name, issue.GetNumber())
+		if err != nil {
+			return fmt.Errorf("failed to get pull request: %w", err)
+		}
+
+		prs = append(prs, pr)
+	}
+
+	base := prs[0].Base.GetRef()
+	branch := QueueBranchPrefix + base
+
+	sha, err := r.forge.GetBranch(ctx, r.owner, r.name, branch)
+	if err != nil {
+		if errors.Is(err, forge.ErrNotFound) {
+			logger.Info().Msgf("The integration branch %s doesn't exist: the batch is canceled.", branch)
+			return r.resetBatch(ctx, prs, branch, "the integration branch doesn't exist")
+		}
+
+		return fmt.Errorf("failed to get the integration branch %s: %w", branch, err)
+	}
+
+	cc, err := r.forge.CompareCommits(ctx, r.owner, r.name, base, sha)
+	if err != nil {
+		return fmt.Errorf("failed to compare commits: %w", err)
+	}
+
+	if cc.GetBehindBy() > 0 {
+		logger.Info().Msgf("The base branch %s has changed: the batch is canceled.", base)
+		return r.resetBatch(ctx, prs, branch, "the base branch has changed")
+	}
+
+	// the batch is read only from the head of the integration branch: a merge commit created by the bot.
+	head, err := r.forge.GetCommit(ctx, r.owner, r.name, sha)
+	if err != nil {
+		return fmt.Errorf("failed to get the head of the integration branch %s: %w", branch, err)
+	}
+
+	if !r.isBatchCommit(head) {
+		logger.Info().Msgf("The head of the integration branch %s has not been created by the bot: the batch is canceled.", branch)
+		return r.resetBatch(ctx, prs, branch, "the head of the integration branch has not been created by the bot")
+	}
+
+	batch, ok := sortBatch(prs, parseBatchEntries(head.GetCommit().GetMessage()))
+	if !ok {
+		logger.Info().Msg("The pull requests of the batch have changed: the batch is canceled.")
+		return r.resetBatch(ctx, prs, branch, "the pull requests of the batch have changed")
+	}
+
+	status, err := r.getAggregatedState(ctx, sha)
+	if err != nil {
+		logger.Error().Err(err).Msgf("Checks status of the integration branch %s", branch)
+		return r.bisect(ctx, batch, branch, err)
+	}
+
+	if status == Pending {
+		logger.Info().Msgf("State: pending. Waiting for the CI of the batch %v.", prNumbers(batch))
+		return nil
+	}
+
+	return r.mergeBatch(ctx, batch, branch, sha)
+}
+
+// mergeBatch fast-forwards the base branch to the integration branch.
+// The forge marks the pull requests as merged.
+func (r Repository) mergeBatch(ctx context.Context, batch []*github.PullRequest, branch, sha string) error {
+	logger := log.Ctx(ctx)
+
+	base := batch[0].Base.GetRef()
+
+	recs := make([]*audit.Record, len(batch))
+	for i, pr := range batch {
+		rec := r.newRecord(pr)
+		rec.AddGate("queue checks", true, branch)
+		rec.MergeMethod = conf.MergeMethodMerge
+
+		prCtx := audit.NewContext(ctx, rec)
+
+		// the reviews can change during the CI of the batch.
+		err := r.hasReviewsApprove(prCtx, pr)
+		if err != nil {
+			rec.AddGate("reviews", false, err.Error())
+			rec.Decision = audit.DecisionEscalate
+
+			err = withReason(reasonReviews, fmt.Errorf("error related to reviews: %w", err))
+			rec.Error = r.redact(err.Error())
+
+			errLabel := r.removeLabel(prCtx, pr, r.markers.MergeQueue)
+			ignoreError(ctx, errLabel)
+
+			r.callHuman(prCtx, pr, err)
+			r.writeRecord(ctx, rec)
+
+			logger.Info().Msgf("The reviews of #%d have changed: the batch is canceled.", pr.GetNumber())
+
+			return r.resetBatch(ctx, withoutPR(batch, pr), branch, fmt.Sprintf("the reviews of #%d have changed", pr.GetNumber()))
+		}
+
+		rec.AddGate("reviews", true, fmt.Sprintf("%d approval(s) required", r.getMinReview(pr)))
+
+		recs[i] = rec
+	}
+
+	logger.Info().Msgf("MERGE(queue) %v", prNumbers(batch))
+
+	if !r.dryRun {
+		err := r.forge.FastForward(ctx, r.owner, r.name, base, sha)
+		if err != nil {
+			err = withReason(reasonMerge, fmt.Errorf("failed to fast-forward the branch %s: %w", base, err))
+
+			for i, pr := range batch {
+				recs[i].Decision = audit.DecisionEscalate
+				recs[i].Error = r.redact(err.Error())
+
+				r.callHuman(audit.NewContext(ctx, recs[i]), pr, err)
+				r.writeRecord(ctx, recs[i])
+			}
+
+			r.deleteBranch(ctx, branch)
+
+			return err
+		}
+	}
+
+	for i, pr := range batch {
+		rec := recs[i]
+		rec.Decision = audit.DecisionMerge
+
+		prCtx := audit.NewContext(ctx, rec)
+
+		if !r.dryRun {
+			rec.MergeSHA = sha
+
+			r.observeMerge(prCtx, pr, reasonQueue)
+			r.notify(prCtx, conf.EventMerged, pr, reasonQueue)
+
+			err := r.removeLabels(prCtx, pr, append(r.mergedLabels(), r.markers.MergeQueue))
+			ignoreError(ctx, err)
+		}
+
+		err := r.mjolnir.CloseRelatedIssues(prCtx, pr)
+		ignoreError(ctx, err)
+
+		r.writeRecord(ctx, rec)
+	}
+
+	r.deleteBranch(ctx, branch)
+
+	return nil
+}
+
+// bisect splits a failing batch: the first half is tested again, the second half returns to the queue.
+// When the batch contains only one pull request, it's the culprit.
+func (r Repository) bisect(ctx context.Context, batch []*github.PullRequest, branch string, cause error) error {
+	logger := log.Ctx(ctx)
+
+	if len(batch) == 1 {
+		pr := batch[0]
+
+		rec := r.newRecord(pr)
+		rec.AddGate("queue checks", false, cause.Error())
+		rec.Decision = audit.DecisionEscalate
+
+		err := withReason(reasonChecks, fmt.Errorf("checks failed in the merge queue: %v", cause))
+		rec.Error = r.redact(err.Error())
+
+		prCtx := audit.NewContext(ctx, rec)
+
+		errLabel := r.removeLabel(prCtx, pr, r.markers.MergeQueue)
+		ignoreError(ctx, errLabel)
+
+		r.callHuman(prCtx, pr, err)
+		r.writeRecord(ctx, rec)
+
+		r.deleteBranch(ctx, branch)
+
+		return nil
+	}
+
+	half := len(batch) / 2
+
+	logger.Info().Msgf("BISECT %v: testing %v", prNumbers(batch), prNumbers(batch[:half]))
+
+	for _, pr := range batch[half:] {
+		rec := r.newRecord(pr)
+		rec.AddGate("queue checks", false, fmt.Sprintf("bisect: testing %v", prNumbers(batch[:half])))
+		rec.Decision = audit.DecisionWait
+
+		err := r.removeLabel(audit.NewContext(ctx, rec), pr, r.markers.MergeQueue)
+		ignoreError(ctx, err)
+
+		r.writeRecord(ctx, rec)
+	}
+
+	var recs []*audit.Record
+	for _, pr := range batch[:half] {
+		rec := r.newRecord(pr)
+		rec.AddGate("queue checks", false, cause.Error())
+
+		recs = append(recs, rec)
+	}
+
+	return r.queueBatch(ctx, batch[:half], recs)
+}
+
+// resetBatch cancels the current batch: the pull requests return to the queue.
+func (r Repository) resetBatch(ctx context.Context, prs []*github.PullRequest, branch, reason string) error {
+	for _, pr := range prs {
+		rec := r.newRecord(pr)
+		rec.AddGate("batch", false, reason)
+		rec.Decision = audit.DecisionWait
+
+		err := r.removeLabel(audit.NewContext(ctx, rec), pr, r.markers.MergeQueue)
+		ignoreError(ctx, err)
+
+		r.writeRecord(ctx, rec)
+	}
+
+	r.deleteBranch(ctx, branch)
+
+	return nil
+}
+
+func (r Repository) deleteBranch(ctx context.Context, branch string) {
+	log.Ctx(ctx).Debug().Msgf("Delete branch: %s. Dry run: %v", branch, r.dryRun)
+
+	if r.dryRun {
+		return
+	}
+
+	err := r.forge.DeleteBranch(ctx, r.owner, r.name, branch)
+	ignoreError(ctx, err)
+}
+
+func (r Repository) mergedLabels() []string {
+	return []string{
+		r.markers.MergeInProgress,
+		r.markers.NeedMerge,
+		r.markers.LightReview,
+		r.markers.MergeMethodPrefix + conf.MergeMethodSquash,
+		r.markers.MergeMethodPrefix + conf.MergeMethodMerge,
+		r.markers.MergeMethodPrefix + conf.MergeMethodRebase,
+		r.markers.MergeMethodPrefix + conf.MergeMethodFastForward,
+	}
+}
+
+// queueCommitMessage creates the message of the merge commit of a pull request in the integration branch.
+func queueCommitMessage(pr *github.PullRequest) string {
+	return fmt.Sprintf("Merge pull request #%d from %s\n\n%s", pr.GetNumber(), pr.Head.GetLabel(), pr.GetTitle())
+}
+
+// batchCommitMessage creates the message of the head of the integration branch (the merge commit of the last pull request):
+// the pull requests of the batch are written as trailers.
+func batchCommitMessage(batch []*github.PullRequest) string {
+	var trailers []string
+	for _, pr := range batch {
+		trailers = append(trailers, fmt.Sprintf("Lobicornis-Batch: #%d %s", pr.GetNumber(), pr.Head.GetSHA()))
+	}
+
+	return queueCommitMessage(batch[len(batch)-1]) + "\n\n" + strings.Join(trailers, "\n")
+}
+
+// withMessage sets the message of a commit (commit.Message quotes the message).
+func withMessage(msg string) types.Option {
+	return func(g *types.Cmd) {
+		g.AddOptions("-m")
+		g.AddOptions(msg)
+	}
+}
+
+// isBatchCommit checks if a commit is the head of an integration branch created by the bot:
+// a merge commit, committed with the git email of the bot (if any).
+func (r Repository) isBatchCommit(rc *github.RepositoryCommit) bool {
+	if len(rc.Parents) != 2 {
+		return false
+	}
+
+	return r.clone.git.Email == "" || strings.EqualFold(rc.GetCommit().GetCommitter().GetEmail(), r.clone.git.Email)
+}
+
+// parseBatchEntries extracts the pull requests of a batch from the message of the head of an integration branch.
+func parseBatchEntries(message string) []queueEntry {
+	var entries []queueEntry
+	for _, match := range batchTrailer.FindAllStringSubmatch(message, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, queueEntry{number: number, sha: match[2]})
+	}
+
+	return entries
+}
+
+// sortBatch sorts the pull requests in the order of the integration branch.
+// Returns false if the pull requests don't match the integration branch.
+func sortBatch(prs []*github.PullRequest, entries []queueEntry) ([]*github.PullRequest, bool) {
+	if len(prs) != len(entries) {
+		return nil, false
+	}
+
+	var batch []*github.PullRequest
+	for _, entry := range entries {
+		var found bool
+		for _, pr := range prs {
+			if pr.GetNumber() == entry.number && pr.Head.GetSHA() == entry.sha {
+				batch = append(batch, pr)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return batch, true
+}
+
+func hasIssueLabel(issue *github.Issue, label string) bool {
+	for _, lbl := range issue.Labels {
+		if lbl.GetName() == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsPR(prs []*github.PullRequest, pr *github.PullRequest) bool {
+	for _, p := range prs {
+		if p.GetNumber() == pr.GetNumber() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutPR returns the pull requests without a pull request.
+func withoutPR(prs []*github.PullRequest, pr *github.PullRequest) []*github.PullRequest {
+	var others []*github.PullRequest
+	for _, p := range prs {
+		if p.GetNumber() != pr.GetNumber() {
+			others = append(others, p)
+		}
+	}
+
+	return others
+}
+
+func prNumbers(prs []*github.PullRequest) []int {
+	var numbers []int
+	for _, pr := range prs {
+		numbers = append(numbers, pr.GetNumber())
+	}
+
+	return numbers
+}
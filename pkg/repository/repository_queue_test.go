@@ -0,0 +1,464 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/ldez/go-git-cmd-wrapper/add"
+	"github.com/ldez/go-git-cmd-wrapper/checkout"
+	"github.com/ldez/go-git-cmd-wrapper/clone"
+	"github.com/ldez/go-git-cmd-wrapper/commit"
+	"github.com/ldez/go-git-cmd-wrapper/git"
+	"github.com/ldez/go-git-cmd-wrapper/revparse"
+	"github.com/ldez/go-git-cmd-wrapper/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+)
+
+func Test_parseBatchEntries(t *testing.T) {
+	message := batchCommitMessage([]*github.PullRequest{
+		newQueuePullRequest(12, "0123456789abcdef0123456789abcdef01234567"),
+		newQueuePullRequest(13, "89abcdef0123456789abcdef0123456789abcdef"),
+	}) + "\nLobicornis-Batch: #14 invalid"
+
+	entries := parseBatchEntries(message)
+
+	expected := []queueEntry{
+		{number: 12, sha: "0123456789abcdef0123456789abcdef01234567"},
+		{number: 13, sha: "89abcdef0123456789abcdef0123456789abcdef"},
+	}
+
+	assert.Equal(t, expected, entries)
+}
+
+func TestRepository_isBatchCommit(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		email    string
+		parents  int
+		expected bool
+	}{
+		{
+			desc:     "merge commit of the bot",
+			email:    "bot@example.com",
+			parents:  2,
+			expected: true,
+		},
+		{
+			desc:    "not a merge commit",
+			email:   "bot@example.com",
+			parents: 1,
+		},
+		{
+			desc:    "committed by someone else",
+			email:   "foo@example.com",
+			parents: 2,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rc := &github.RepositoryCommit{
+				Commit: &github.Commit{Committer: &github.CommitAuthor{Email: github.String(test.email)}},
+			}
+
+			for i := 0; i < test.parents; i++ {
+				rc.Parents = append(rc.Parents, &github.Commit{})
+			}
+
+			repo := Repository{clone: newClone(conf.Git{Email: "bot@example.com"}, "", nil)}
+
+			assert.Equal(t, test.expected, repo.isBatchCommit(rc))
+		})
+	}
+}
+
+func Test_sortBatch(t *testing.T) {
+	pr1 := &github.PullRequest{Number: github.Int(1), Head: &github.PullRequestBranch{SHA: github.String("aaa")}}
+	pr2 := &github.PullRequest{Number: github.Int(2), Head: &github.PullRequestBranch{SHA: github.String("bbb")}}
+
+	testCases := []struct {
+		desc     string
+		entries  []queueEntry
+		expected []*github.PullRequest
+		ok       bool
+	}{
+		{
+			desc:     "integration branch order",
+			entries:  []queueEntry{{number: 2, sha: "bbb"}, {number: 1, sha: "aaa"}},
+			expected: []*github.PullRequest{pr2, pr1},
+			ok:       true,
+		},
+		{
+			desc:    "changed pull request",
+			entries: []queueEntry{{number: 1, sha: "aaa"}, {number: 2, sha: "ccc"}},
+		},
+		{
+			desc:    "missing pull request",
+			entries: []queueEntry{{number: 1, sha: "aaa"}},
+		},
+		{
+			desc:    "unknown pull request",
+			entries: []queueEntry{{number: 1, sha: "aaa"}, {number: 3, sha: "bbb"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			batch, ok := sortBatch([]*github.PullRequest{pr1, pr2}, test.entries)
+
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, batch)
+		})
+	}
+}
+
+func TestRepository_mergeBatch_audit(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		review   string
+		expected []audit.Record
+	}{
+		{
+			desc:   "merge",
+			review: Approved,
+			expected: []audit.Record{
+				{
+					Repo: "foo/bar", PR: 1, HeadSHA: "aaa", DryRun: true,
+					Gates: []audit.Gate{
+						{Name: "queue checks", Passed: true, Detail: "lobicornis-queue/master"},
+						{Name: "reviews", Passed: true, Detail: "1 approval(s) required"},
+					},
+					Reviews:     []audit.Review{{User: "bar", State: Approved}},
+					MergeMethod: conf.MergeMethodMerge,
+					Decision:    audit.DecisionMerge,
+				},
+				{
+					Repo: "foo/bar", PR: 2, HeadSHA: "bbb", DryRun: true,
+					Gates: []audit.Gate{
+						{Name: "queue checks", Passed: true, Detail: "lobicornis-queue/master"},
+						{Name: "reviews", Passed: true, Detail: "1 approval(s) required"},
+					},
+					Reviews:     []audit.Review{{User: "bar", State: Approved}},
+					MergeMethod: conf.MergeMethodMerge,
+					Decision:    audit.DecisionMerge,
+				},
+			},
+		},
+		{
+			desc:   "changes requested",
+			review: "CHANGES_REQUESTED",
+			expected: []audit.Record{
+				{
+					Repo: "foo/bar", PR: 1, HeadSHA: "aaa", DryRun: true,
+					Gates: []audit.Gate{
+						{Name: "queue checks", Passed: true, Detail: "lobicornis-queue/master"},
+						{Name: "reviews", Passed: false, Detail: "CHANGES_REQUESTED by bar"},
+					},
+					Reviews:       []audit.Review{{User: "bar", State: "CHANGES_REQUESTED"}},
+					MergeMethod:   conf.MergeMethodMerge,
+					LabelsAdded:   []string{"bot/need-human-merge"},
+					LabelsRemoved: []string{"bot/merge-queue"},
+					Comments:      []string{":no_entry_sign: error related to reviews: CHANGES_REQUESTED by bar"},
+					Decision:      audit.DecisionEscalate,
+					Error:         "error related to reviews: CHANGES_REQUESTED by bar",
+				},
+				{
+					Repo: "foo/bar", PR: 2, HeadSHA: "bbb", DryRun: true,
+					Gates: []audit.Gate{
+						{Name: "batch", Passed: false, Detail: "the reviews of #1 have changed"},
+					},
+					LabelsRemoved: []string{"bot/merge-queue"},
+					Decision:      audit.DecisionWait,
+				},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reviews := []*github.PullRequestReview{
+				{User: &github.User{Login: github.String("bar")}, State: github.String(test.review)},
+			}
+
+			var batch []*github.PullRequest
+			for i, sha := range []string{"aaa", "bbb"} {
+				pr := makePullRequestWithLabels([]string{"bot/merge-queue"}, i+1)
+				pr.Head = &github.PullRequestBranch{SHA: github.String(sha)}
+				pr.Base = &github.PullRequestBranch{Ref: github.String("master")}
+
+				batch = append(batch, pr)
+			}
+
+			markers := conf.Markers{
+				NeedHumanMerge: "bot/need-human-merge",
+				MergeQueue:     "bot/merge-queue",
+			}
+
+			config := conf.RepoConfig{
+				MinReview:         conf.Int(1),
+				AddErrorInComment: conf.Bool(true),
+			}
+
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+			repo := New(explainForge{reviews: reviews}, "foo/bar", "", markers, conf.Retry{}, conf.Git{}, config, conf.Extra{DryRun: true}, nil, audit.New(conf.Audit{File: path}), nil)
+
+			err := repo.mergeBatch(context.Background(), batch, QueueBranchPrefix+"master", "ccc")
+			require.NoError(t, err)
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+
+			t.Cleanup(func() { _ = file.Close() })
+
+			records, err := audit.Query(file, audit.Filter{})
+			require.NoError(t, err)
+			require.Len(t, records, len(test.expected))
+
+			for i := range records {
+				assert.NotZero(t, records[i].Time)
+				records[i].Time = test.expected[i].Time
+			}
+
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRepository_mergeIntoIntegrationBranch(t *testing.T) {
+	ctx := context.Background()
+
+	origin := createRepository(t)
+
+	sha1 := createPullRequestRef(t, origin, 1, "a.txt", "a")
+	sha2 := createPullRequestRef(t, origin, 2, "b.txt", "b")
+	sha3 := createPullRequestRef(t, origin, 3, "a.txt", "conflict")
+
+	ws, err := NewWorkspace("")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ws.Remove() })
+
+	output, err := git.Clone(clone.Repository(origin), clone.Directory("."), ws.executor(ctx))
+	require.NoError(t, err, output)
+
+	output, err = configureGitUserInfo(ctx, "hubert", "hubert@foo.com", ws)
+	require.NoError(t, err, output)
+
+	output, err = git.Checkout(checkout.NewBranchForce(QueueBranchPrefix+"master"), ws.executor(ctx))
+	require.NoError(t, err, output)
+
+	repo := Repository{}
+
+	testCases := []struct {
+		desc     string
+		pr       *github.PullRequest
+		expected bool
+	}{
+		{
+			desc:     "merged",
+			pr:       newQueuePullRequest(1, sha1),
+			expected: true,
+		},
+		{
+			desc:     "changed pull request",
+			pr:       newQueuePullRequest(2, sha1),
+			expected: false,
+		},
+		{
+			desc:     "merged",
+			pr:       newQueuePullRequest(2, sha2),
+			expected: true,
+		},
+		{
+			desc:     "conflict",
+			pr:       newQueuePullRequest(3, sha3),
+			expected: false,
+		},
+	}
+
+	// the merges are cumulative: the test cases are sequential.
+	for _, test := range testCases {
+		ok, err := repo.mergeIntoIntegrationBranch(ctx, test.pr, ws)
+		require.NoError(t, err, test.desc)
+
+		assert.Equal(t, test.expected, ok, test.desc)
+	}
+
+	// only the head of the integration branch describes the batch.
+	output, err = git.Commit(commit.Amend, withMessage(batchCommitMessage([]*github.PullRequest{newQueuePullRequest(1, sha1), newQueuePullRequest(2, sha2)})), ws.executor(ctx))
+	require.NoError(t, err, output)
+
+	output, err = git.Raw("log", func(g *types.Cmd) {
+		g.AddOptions("-1")
+		g.AddOptions("--format=%P%n%B")
+	}, ws.executor(ctx))
+	require.NoError(t, err, output)
+
+	// the head is still a merge commit.
+	assert.Len(t, strings.Fields(strings.SplitN(output, "\n", 2)[0]), 2)
+
+	expected := []queueEntry{{number: 1, sha: sha1}, {number: 2, sha: sha2}}
+	assert.Equal(t, expected, parseBatchEntries(output))
+
+	// the conflicting merge has been aborted.
+	output, err = git.Raw("status", func(g *types.Cmd) {
+		g.AddOptions("--porcelain")
+	}, ws.executor(ctx))
+	require.NoError(t, err, output)
+	assert.Empty(t, output)
+}
+
+// checkBatchForge a forge with a batch of one pull request: the checks come from a GitHub API.
+type checkBatchForge struct {
+	forge.Forge
+
+	pr   *github.PullRequest
+	head *github.RepositoryCommit
+}
+
+func (f checkBatchForge) GetPullRequest(_ context.Context, _, _ string, _ int) (*github.PullRequest, error) {
+	return f.pr, nil
+}
+
+func (f checkBatchForge) GetBranch(_ context.Context, _, _, _ string) (string, error) {
+	return f.head.GetSHA(), nil
+}
+
+func (f checkBatchForge) CompareCommits(_ context.Context, _, _, _, _ string) (*github.CommitsComparison, error) {
+	return &github.CommitsComparison{BehindBy: github.Int(0)}, nil
+}
+
+func (f checkBatchForge) GetCommit(_ context.Context, _, _, _ string) (*github.RepositoryCommit, error) {
+	return f.head, nil
+}
+
+func TestRepository_checkBatch_failedCheckSuite(t *testing.T) {
+	const headSHA = "0123456789abcdef0123456789abcdef01234567"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/foo/bar/commits/ccc/status", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(rw, `{"state": "success", "total_count": 1}`)
+	})
+	mux.HandleFunc("/repos/foo/bar/commits/ccc/check-suites", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(rw, `{"total_count": 2, "check_suites": [
+			{"app": {"name": "GitHub Actions"}, "status": "completed", "conclusion": "success"},
+			{"app": {"name": "Semaphore"}, "status": "completed", "conclusion": "failure"}
+		]}`)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	pr := makePullRequestWithLabels([]string{"bot/merge-queue"}, 1)
+	pr.Head = &github.PullRequestBranch{SHA: github.String(headSHA)}
+	pr.Base = &github.PullRequestBranch{Ref: github.String("master")}
+
+	frg := checkBatchForge{
+		Forge: forge.NewGitHub(client, "foo"),
+		pr:    pr,
+		head: &github.RepositoryCommit{
+			SHA: github.String("ccc"),
+			Commit: &github.Commit{
+				Message: github.String("Merge #1\n\nLobicornis-Batch: #1 " + headSHA),
+			},
+			Parents: []*github.Commit{{}, {}},
+		},
+	}
+
+	markers := conf.Markers{
+		NeedHumanMerge: "bot/need-human-merge",
+		MergeQueue:     "bot/merge-queue",
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	repo := New(frg, "foo/bar", "", markers, conf.Retry{}, conf.Git{}, conf.RepoConfig{}, conf.Extra{DryRun: true}, nil, audit.New(conf.Audit{File: path}), nil)
+
+	err := repo.checkBatch(context.Background(), []*github.Issue{{Number: github.Int(1)}})
+	require.NoError(t, err)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = file.Close() })
+
+	records, err := audit.Query(file, audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// the batch of one pull request is bisected: the pull request needs a human.
+	assert.Equal(t, audit.DecisionEscalate, records[0].Decision)
+	assert.Equal(t, []audit.Gate{{Name: "queue checks", Passed: false, Detail: "Semaphore completed failure"}}, records[0].Gates)
+}
+
+func newQueuePullRequest(number int, sha string) *github.PullRequest {
+	return &github.PullRequest{
+		Number: github.Int(number),
+		Title:  github.String("title"),
+		Head: &github.PullRequestBranch{
+			Label: github.String("foo:bar"),
+			SHA:   github.String(sha),
+		},
+	}
+}
+
+// createPullRequestRef creates a commit on top of master, and references it as the head of a pull request.
+func createPullRequestRef(t *testing.T, dir string, number int, filename, content string) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	output, err := git.Checkout(checkout.Detach, checkout.Branch("master"), dirExecutor(ctx, dir))
+	require.NoError(t, err, output)
+
+	err = ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0o600)
+	require.NoError(t, err)
+
+	output, err = git.Add(add.PathSpec(filename), dirExecutor(ctx, dir))
+	require.NoError(t, err, output)
+
+	output, err = git.Commit(commit.Message(content), dirExecutor(ctx, dir))
+	require.NoError(t, err, output)
+
+	sha, err := git.RevParse(revparse.Args("HEAD"), dirExecutor(ctx, dir))
+	require.NoError(t, err, sha)
+
+	sha = strings.TrimSpace(sha)
+
+	output, err = git.Raw("update-ref", func(g *types.Cmd) {
+		g.AddOptions(fmt.Sprintf("refs/pull/%d/head", number))
+		g.AddOptions(sha)
+	}, dirExecutor(ctx, dir))
+	require.NoError(t, err, output)
+
+	output, err = git.Checkout(checkout.Branch("master"), dirExecutor(ctx, dir))
+	require.NoError(t, err, output)
+
+	return sha
+}
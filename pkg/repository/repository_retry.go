@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/go-github/v32/github"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
 )
 
 func (r Repository) cleanRetryLabel(ctx context.Context, pr *github.PullRequest) {
@@ -47,7 +48,10 @@ func (r Repository) manageRetryLabel(ctx context.Context, pr *github.PullRequest
 	number := extractRetryNumber(currentRetryLabel, r.markers.MergeRetryPrefix)
 
 	if number >= r.retry.Number {
-		return fmt.Errorf("too many retry [%d/%d]: %w", number, r.retry.Number, rootErr)
+		err = fmt.Errorf("too many retry [%d/%d]: %w", number, r.retry.Number, rootErr)
+		r.notify(ctx, conf.EventRetryExhausted, pr, err.Error())
+
+		return err
 	}
 
 	// retry
@@ -3,15 +3,18 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/google/go-github/v32/github"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
 )
 
 const (
 	// Pending Check state.
-	Pending = "pending"
+	Pending = forge.Pending
 	// Success Check state.
-	Success = "success"
+	Success = forge.Success
 
 	// Approved Review state.
 	Approved = "APPROVED"
@@ -29,30 +32,29 @@ func (r Repository) hasReviewsApprove(ctx context.Context, pr *github.PullReques
 		return nil
 	}
 
-	opt := &github.ListOptions{
-		PerPage: 100,
+	reviews, err := r.forge.ListReviews(ctx, r.owner, r.name, pr.GetNumber())
+	if err != nil {
+		return err
 	}
 
 	reviewsState := make(map[string]string)
-	for {
-		reviews, resp, err := r.client.PullRequests.ListReviews(ctx, r.owner, r.name, pr.GetNumber(), opt)
-		if err != nil {
-			return err
-		}
-
-		for _, review := range reviews {
-			if review.GetState() == Dismissed {
-				delete(reviewsState, review.User.GetLogin())
-			} else if review.GetState() != Commented {
-				reviewsState[review.User.GetLogin()] = review.GetState()
-			}
+	for _, review := range reviews {
+		if review.GetState() == Dismissed {
+			delete(reviewsState, review.User.GetLogin())
+		} else if review.GetState() != Commented {
+			reviewsState[review.User.GetLogin()] = review.GetState()
 		}
+	}
 
-		if resp.NextPage == 0 {
-			break
-		}
+	logins := make([]string, 0, len(reviewsState))
+	for login := range reviewsState {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
 
-		opt.Page = resp.NextPage
+	rec := audit.FromContext(ctx)
+	for _, login := range logins {
+		rec.Reviews = append(rec.Reviews, audit.Review{User: login, State: reviewsState[login]})
 	}
 
 	if len(reviewsState) < minReview {
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/lobicornis/v2/pkg/audit"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+)
+
+func TestRepository_Process_audit(t *testing.T) {
+	approved := []*github.PullRequestReview{
+		{User: &github.User{Login: github.String("bar")}, State: github.String(Approved)},
+	}
+
+	testCases := []struct {
+		desc      string
+		milestone bool
+		state     string
+		freeze    string
+		expected  audit.Record
+	}{
+		{
+			desc:      "merge",
+			milestone: true,
+			state:     Success,
+			expected: audit.Record{
+				Repo:    "foo/bar",
+				PR:      1,
+				HeadSHA: "aaa",
+				DryRun:  true,
+				Gates: []audit.Gate{
+					{Name: "milestone", Passed: true, Detail: "v1.0"},
+					{Name: "reviews", Passed: true, Detail: "1 approval(s) required"},
+					{Name: "checks", Passed: true, Detail: Success},
+					{Name: "mergeability", Passed: true},
+					{Name: "merge method", Passed: true, Detail: conf.MergeMethodSquash},
+					{Name: "up-to-date", Passed: true, Detail: "update required: true"},
+					{Name: "merge window", Passed: true},
+				},
+				Reviews:       []audit.Review{{User: "bar", State: Approved}},
+				MergeMethod:   conf.MergeMethodSquash,
+				LabelsRemoved: []string{"bot/merge-in-progress"},
+				Decision:      audit.DecisionMerge,
+			},
+		},
+		{
+			desc:      "merges frozen",
+			milestone: true,
+			state:     Success,
+			freeze:    "release v2",
+			expected: audit.Record{
+				Repo:    "foo/bar",
+				PR:      1,
+				HeadSHA: "aaa",
+				DryRun:  true,
+				Gates: []audit.Gate{
+					{Name: "milestone", Passed: true, Detail: "v1.0"},
+					{Name: "reviews", Passed: true, Detail: "1 approval(s) required"},
+					{Name: "checks", Passed: true, Detail: Success},
+					{Name: "mergeability", Passed: true},
+					{Name: "merge method", Passed: true, Detail: conf.MergeMethodSquash},
+					{Name: "up-to-date", Passed: true, Detail: "update required: true"},
+					{Name: "merge window", Passed: false, Detail: ".github/lobicornis.freeze: release v2"},
+				},
+				Reviews:     []audit.Review{{User: "bar", State: Approved}},
+				MergeMethod: conf.MergeMethodSquash,
+				Decision:    audit.DecisionWait,
+			},
+		},
+		{
+			desc:      "wait for the CI",
+			milestone: true,
+			state:     Pending,
+			expected: audit.Record{
+				Repo:    "foo/bar",
+				PR:      1,
+				HeadSHA: "aaa",
+				DryRun:  true,
+				Gates: []audit.Gate{
+					{Name: "milestone", Passed: true, Detail: "v1.0"},
+					{Name: "reviews", Passed: true, Detail: "1 approval(s) required"},
+					{Name: "checks", Passed: false, Detail: Pending},
+				},
+				Reviews:  []audit.Review{{User: "bar", State: Approved}},
+				Decision: audit.DecisionWait,
+			},
+		},
+		{
+			desc:  "escalate",
+			state: Success,
+			expected: audit.Record{
+				Repo:    "foo/bar",
+				PR:      1,
+				HeadSHA: "aaa",
+				DryRun:  true,
+				Gates: []audit.Gate{
+					{Name: "milestone", Passed: false},
+				},
+				LabelsAdded:   []string{"bot/need-human-merge"},
+				LabelsRemoved: []string{"bot/merge-in-progress"},
+				Comments:      []string{":no_entry_sign: the milestone is missing"},
+				Decision:      audit.DecisionEscalate,
+				Error:         "the milestone is missing",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			pr := makePullRequestWithLabels([]string{"bot/merge", "bot/merge-in-progress"}, 1)
+			pr.Mergeable = github.Bool(true)
+			pr.Head = &github.PullRequestBranch{SHA: github.String("aaa")}
+			pr.Base = &github.PullRequestBranch{Ref: github.String("master")}
+			if test.milestone {
+				pr.Milestone = &github.Milestone{Title: github.String("v1.0")}
+			}
+
+			frg := explainForge{pr: pr, reviews: approved, state: test.state, freeze: test.freeze}
+
+			markers := conf.Markers{
+				NeedMerge:         "bot/merge",
+				MergeInProgress:   "bot/merge-in-progress",
+				NeedHumanMerge:    "bot/need-human-merge",
+				MergeMethodPrefix: "bot/merge-method-",
+			}
+
+			config := conf.RepoConfig{
+				MergeMethod:       conf.String(conf.MergeMethodSquash),
+				MinReview:         conf.Int(1),
+				NeedMilestone:     conf.Bool(true),
+				CheckNeedUpToDate: conf.Bool(true),
+				AddErrorInComment: conf.Bool(true),
+			}
+
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+			repo := New(frg, "foo/bar", "", markers, conf.Retry{}, conf.Git{}, config, conf.Extra{DryRun: true}, nil, audit.New(conf.Audit{File: path}), nil)
+
+			_ = repo.Process(context.Background(), 1)
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+
+			t.Cleanup(func() { _ = file.Close() })
+
+			records, err := audit.Query(file, audit.Filter{})
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+
+			assert.NotZero(t, records[0].Time)
+			records[0].Time = test.expected.Time
+
+			assert.Equal(t, test.expected, records[0])
+		})
+	}
+}
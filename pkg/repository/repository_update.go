@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"strings"
 
 	"github.com/google/go-github/v32/github"
@@ -15,12 +13,16 @@ import (
 	"github.com/ldez/go-git-cmd-wrapper/rebase"
 	"github.com/ldez/go-git-cmd-wrapper/types"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/metrics"
 )
 
 // Merge action.
 const (
 	ActionMerge  = "merge"
 	ActionRebase = "rebase"
+	// ActionForge the update with the forge API (update button).
+	ActionForge = "forge"
 )
 
 func (r *Repository) update(ctx context.Context, pr *github.PullRequest) error {
@@ -32,18 +34,21 @@ func (r *Repository) update(ctx context.Context, pr *github.PullRequest) error {
 		logger.Error().Err(err).Msg("unable to add labels")
 	}
 
-	// use GitHub API (update button)
+	// use the forge API (update button)
 	if !pr.GetMaintainerCanModify() && !isOnMainRepository(pr) {
 		if r.dryRun {
-			logger.Debug().Msg("Updated via a merge with the GitHub API.")
+			logger.Debug().Msg("Updated via a merge with the forge API.")
 			return nil
 		}
 
-		_, _, err := r.client.PullRequests.UpdateBranch(ctx, pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(), pr.GetNumber(), nil)
+		err := r.forge.UpdateBranch(ctx, r.owner, r.name, pr.GetNumber())
 		if err != nil {
 			return fmt.Errorf("update branch: %w", err)
 		}
 
+		metrics.Updated.WithLabelValues(r.fullName(), ActionForge).Inc()
+		r.notify(ctx, conf.EventUpdated, pr, ActionForge)
+
 		return nil
 	}
 
@@ -56,31 +61,25 @@ func (r *Repository) cloneAndUpdate(ctx context.Context, pr *github.PullRequest)
 
 	logger.Info().Msgf("Base branch: %s - Fork branch: %s", pr.Base.GetRef(), pr.Head.GetRef())
 
-	dir, err := ioutil.TempDir("", "myrmica-lobicornis")
+	ws, err := NewWorkspace(r.clone.git.WorkDir)
 	if err != nil {
 		return err
 	}
 
-	defer func() { ignoreError(ctx, os.RemoveAll(dir)) }()
+	defer func() { ignoreError(ctx, ws.Remove()) }()
 
-	err = os.Chdir(dir)
-	if err != nil {
-		return err
-	}
-
-	tempDir, _ := os.Getwd()
-	logger.Info().Msg(tempDir)
+	logger.Info().Msg(ws.Dir())
 
 	if isOnMainRepository(pr) && pr.Head.GetRef() == mainBranch {
 		return errors.New("the branch master on a main repository cannot be rebased")
 	}
 
-	mainRemote, err := r.clone.PullRequestForUpdate(ctx, pr)
+	mainRemote, err := r.clone.PullRequestForUpdate(ctx, pr, ws)
 	if err != nil {
 		return fmt.Errorf("failed to clone: %w", err)
 	}
 
-	output, err := r.updatePullRequest(ctx, pr, mainRemote)
+	output, err := r.updatePullRequest(ctx, pr, mainRemote, ws)
 	logger.Info().Msg(output)
 
 	if err != nil {
@@ -91,8 +90,8 @@ func (r *Repository) cloneAndUpdate(ctx context.Context, pr *github.PullRequest)
 }
 
 // updatePullRequest Update a pull request.
-func (r *Repository) updatePullRequest(ctx context.Context, pr *github.PullRequest, mainRemote string) (string, error) {
-	action, err := r.getUpdateAction(ctx, pr)
+func (r *Repository) updatePullRequest(ctx context.Context, pr *github.PullRequest, mainRemote string, ws *Workspace) (string, error) {
+	action, err := r.getUpdateAction(ctx, pr, ws)
 	if err != nil {
 		return "", err
 	}
@@ -103,7 +102,7 @@ func (r *Repository) updatePullRequest(ctx context.Context, pr *github.PullReque
 		logger.Info().Msg("Rebase")
 
 		// rebase
-		output, errRebase := rebasePR(pr, mainRemote, r.debug)
+		output, errRebase := rebasePR(ctx, pr, mainRemote, ws, r.debug)
 		if errRebase != nil {
 			logger.Error().Err(errRebase).Msg("unable to rebase PR")
 			return output, fmt.Errorf("failed to rebase:\n %s", output)
@@ -112,7 +111,7 @@ func (r *Repository) updatePullRequest(ctx context.Context, pr *github.PullReque
 		logger.Info().Msg("Merge")
 
 		// merge
-		output, errMerge := mergeBaseHeadIntoPR(pr, mainRemote, r.debug)
+		output, errMerge := mergeBaseHeadIntoPR(ctx, pr, mainRemote, ws, r.debug)
 		if errMerge != nil {
 			logger.Error().Err(errMerge).Msg("unable to merge base head into PR")
 			return output, fmt.Errorf("failed to merge base HEAD:\n %s", output)
@@ -125,15 +124,22 @@ func (r *Repository) updatePullRequest(ctx context.Context, pr *github.PullReque
 		git.Cond(action == ActionRebase, push.ForceWithLease),
 		push.Remote(RemoteOrigin),
 		push.RefSpec(pr.Head.GetRef()),
-		git.Debugger(r.debug))
+		git.Debugger(r.debug),
+		ws.executor(ctx))
 	if err != nil {
 		return output, fmt.Errorf("failed to push branch %s: %w\n %s", pr.Head.GetRef(), err, output)
 	}
 
+	if !r.dryRun {
+		metrics.Updated.WithLabelValues(r.fullName(), action).Inc()
+	}
+
+	r.notify(ctx, conf.EventUpdated, pr, action)
+
 	return output, nil
 }
 
-func (r *Repository) getUpdateAction(ctx context.Context, pr *github.PullRequest) (string, error) {
+func (r *Repository) getUpdateAction(ctx context.Context, pr *github.PullRequest, ws *Workspace) (string, error) {
 	// find the first commit of the PR
 	firstCommit, err := r.findFirstCommit(ctx, pr)
 	if err != nil {
@@ -145,7 +151,7 @@ func (r *Repository) getUpdateAction(ctx context.Context, pr *github.PullRequest
 		g.AddOptions("--oneline")
 		g.AddOptions("--merges")
 		g.AddOptions(fmt.Sprintf("%s^..HEAD", firstCommit.GetSHA()))
-	})
+	}, ws.executor(ctx))
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg(output)
 		return "", fmt.Errorf("failed to display git log: %w", err)
@@ -162,31 +168,20 @@ func (r *Repository) getUpdateAction(ctx context.Context, pr *github.PullRequest
 
 // findFirstCommit find the first commit of a PR.
 func (r *Repository) findFirstCommit(ctx context.Context, pr *github.PullRequest) (*github.RepositoryCommit, error) {
-	options := &github.ListOptions{
-		PerPage: 1,
-	}
-
-	commits, _, err := r.client.PullRequests.ListCommits(
-		ctx,
-		pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(),
-		pr.GetNumber(),
-		options)
-	if err != nil {
-		return nil, err
-	}
-
-	return commits[0], nil
+	return r.forge.GetFirstCommit(ctx, r.owner, r.name, pr.GetNumber())
 }
 
-func rebasePR(pr *github.PullRequest, remoteName string, debug bool) (string, error) {
+func rebasePR(ctx context.Context, pr *github.PullRequest, remoteName string, ws *Workspace, debug bool) (string, error) {
 	return git.Rebase(
 		rebase.PreserveMerges,
 		rebase.Branch(fmt.Sprintf("%s/%s", remoteName, pr.Base.GetRef())),
-		git.Debugger(debug))
+		git.Debugger(debug),
+		ws.executor(ctx))
 }
 
-func mergeBaseHeadIntoPR(pr *github.PullRequest, remoteName string, debug bool) (string, error) {
+func mergeBaseHeadIntoPR(ctx context.Context, pr *github.PullRequest, remoteName string, ws *Workspace, debug bool) (string, error) {
 	return git.Merge(
 		merge.Commits(fmt.Sprintf("%s/%s", remoteName, pr.Base.GetRef())),
-		git.Debugger(debug))
+		git.Debugger(debug),
+		ws.executor(ctx))
 }
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ldez/go-git-cmd-wrapper/git"
+	"github.com/ldez/go-git-cmd-wrapper/types"
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/lobicornis/v2/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Workspace a sandbox directory dedicated to one git operation.
+// The git commands are executed inside the sandbox: the working directory of the process is never changed.
+type Workspace struct {
+	dir      string
+	cleanups []func()
+}
+
+// NewWorkspace creates a new sandbox directory inside baseDir.
+// If baseDir is empty, the default directory for temporary files is used.
+func NewWorkspace(baseDir string) (*Workspace, error) {
+	dir, err := ioutil.TempDir(baseDir, "myrmica-lobicornis")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Workspace{dir: dir}, nil
+}
+
+// Dir gets the path of the sandbox directory.
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Remove removes the sandbox directory.
+func (w *Workspace) Remove() error {
+	err := os.RemoveAll(w.dir)
+
+	for _, cleanup := range w.cleanups {
+		cleanup()
+	}
+
+	w.cleanups = nil
+
+	return err
+}
+
+// onRemove registers a function called when the sandbox directory is removed.
+func (w *Workspace) onRemove(cleanup func()) {
+	w.cleanups = append(w.cleanups, cleanup)
+}
+
+// executor executes a git command inside the sandbox directory.
+func (w *Workspace) executor(ctx context.Context) types.Option {
+	return dirExecutor(ctx, w.dir)
+}
+
+// dirExecutor executes a git command inside a directory.
+// The command is killed when the context is canceled.
+func dirExecutor(ctx context.Context, dir string) types.Option {
+	return git.CmdExecutor(func(name string, debug bool, args ...string) (string, error) {
+		if debug {
+			log.Ctx(ctx).Debug().Str("dir", dir).Msgf("%s %s", name, strings.Join(args, " "))
+		}
+
+		// the arguments are not recorded: they can contain some credentials.
+		spanCtx, span := tracing.Start(ctx, name+" "+subCommand(args), attribute.String("dir", dir))
+
+		cmd := exec.CommandContext(spanCtx, name, args...)
+		cmd.Dir = dir
+		// never wait for credentials on a terminal.
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+		output, err := cmd.CombinedOutput()
+
+		tracing.End(span, err)
+
+		return string(output), err
+	})
+}
+
+// subCommand gets the git sub-command (ex: clone, fetch, rebase).
+func subCommand(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+
+	return ""
+}
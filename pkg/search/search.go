@@ -2,92 +2,223 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v32/github"
 	"github.com/rs/zerolog/log"
 	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
+	"github.com/traefik/lobicornis/v2/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Finder a pull request search manager.
 type Finder struct {
-	client  *github.Client
-	markers conf.Markers
-	retry   conf.Retry
+	forge    forge.Forge
+	markers  conf.Markers
+	retry    conf.Retry
+	priority conf.Priority
+
+	labeledAt *labeledAtCache
 }
 
 // New creates a new finder.
-func New(client *github.Client, markers conf.Markers, retry conf.Retry) Finder {
+func New(frg forge.Forge, markers conf.Markers, retry conf.Retry, priority conf.Priority) Finder {
 	return Finder{
-		client:  client,
-		markers: markers,
-		retry:   retry,
+		forge:     frg,
+		markers:   markers,
+		retry:     retry,
+		priority:  priority,
+		labeledAt: &labeledAtCache{repos: make(map[string]map[int]labeledAtEntry)},
 	}
 }
 
+// labeledAtCache the times of the label markers.needMerge found by the previous sweeps.
+// A change of the labels updates a pull request: an entry is valid while the time of the last update is unchanged.
+type labeledAtCache struct {
+	mu    sync.Mutex
+	repos map[string]map[int]labeledAtEntry
+}
+
+type labeledAtEntry struct {
+	updatedAt time.Time
+	// labeledAt the time of the label, zero if there is no label event.
+	labeledAt time.Time
+}
+
+func (c *labeledAtCache) get(fullName string) map[int]labeledAtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.repos[fullName]
+}
+
+// set replaces the entries of a repository: the pull requests not found by the sweep are forgotten.
+func (c *labeledAtCache) set(fullName string, entries map[int]labeledAtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.repos[fullName] = entries
+}
+
 // Search searches all PR in all repositories of the user.
 func (f Finder) Search(ctx context.Context, user string, parameters ...Parameter) (map[string][]*github.Issue, error) {
-	query := fmt.Sprintf("user:%s type:pr state:open ", user)
-
+	var criteria forge.Criteria
 	for _, param := range parameters {
 		if param != nil {
-			query += param()
+			param(&criteria)
 		}
 	}
 
-	searchOpts := &github.SearchOptions{
-		Sort:        "updated",
-		Order:       "asc",
-		ListOptions: github.ListOptions{PerPage: 100},
+	ctx, span := tracing.Start(ctx, "Finder.Search", attribute.String("owner", user))
+
+	results, err := f.forge.Search(ctx, user, criteria)
+	tracing.End(span, err)
+
+	return results, err
+}
+
+// The reasons of the choice of the current pull request.
+const (
+	// ReasonFastForward the pull request with the merge method ff.
+	ReasonFastForward = "ff"
+	// ReasonRetry the pull request in retry, after the retry interval.
+	ReasonRetry = "retry"
+	// ReasonRetryInterval no pull request: the pull requests in retry wait for the retry interval.
+	ReasonRetryInterval = "retry interval"
+	// ReasonInProgress the pull request with the label markers.mergeInProgress.
+	ReasonInProgress = "in progress"
+	// ReasonPriority the pull request with the highest priority label (priority.labels).
+	ReasonPriority = "priority"
+	// ReasonOldest the oldest pull request: the least recently updated, or the first labeled with markers.needMerge (priority.labeledAt).
+	ReasonOldest = "oldest"
+)
+
+// Sort sorts the pull requests of a repository in line: the highest priority labels first, then the oldest.
+// The issues must be in the order of the search (the least recently updated first).
+func (f Finder) Sort(ctx context.Context, fullName string, issues []*github.Issue) error {
+	if len(f.priority.Labels) == 0 && !f.priority.LabeledAt {
+		return nil
 	}
 
-	overview := make(map[string][]*github.Issue)
+	ctx, span := tracing.Start(ctx, "Finder.Sort", attribute.String("repo", fullName))
+
+	err := f.sort(ctx, fullName, issues)
+	tracing.End(span, err)
 
-	var count int
-	for {
-		count++
+	return err
+}
 
-		searchResult, resp, err := f.client.Search.Issues(ctx, query, searchOpts)
+func (f Finder) sort(ctx context.Context, fullName string, issues []*github.Issue) error {
+	times := make(map[int]time.Time, len(issues))
+	for _, issue := range issues {
+		times[issue.GetNumber()] = issue.GetUpdatedAt()
+	}
+
+	if f.priority.LabeledAt {
+		err := f.fillLabeledAt(ctx, fullName, issues, times)
 		if err != nil {
-			return nil, err
+			return err
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		pi, pj := f.priorityOf(issues[i]), f.priorityOf(issues[j])
+		if pi != pj {
+			return pi < pj
 		}
 
-		for _, issue := range searchResult.Issues {
-			fullName := getFullName(issue.GetRepositoryURL())
+		return times[issues[i].GetNumber()].Before(times[issues[j].GetNumber()])
+	})
 
-			overview[fullName] = append(overview[fullName], issue)
+	return nil
+}
+
+// fillLabeledAt replaces the time of the last update by the time of the label markers.needMerge,
+// only for the pull requests that share their priority with another pull request (the order of the others doesn't depend on the time).
+func (f Finder) fillLabeledAt(ctx context.Context, fullName string, issues []*github.Issue, times map[int]time.Time) error {
+	index := strings.LastIndex(fullName, "/")
+	owner, name := fullName[:index], fullName[index+1:]
+
+	ranks := make(map[int]int)
+	for _, issue := range issues {
+		ranks[f.priorityOf(issue)]++
+	}
+
+	previous := f.labeledAt.get(fullName)
+	entries := make(map[int]labeledAtEntry)
+
+	for _, issue := range issues {
+		if ranks[f.priorityOf(issue)] < 2 {
+			continue
+		}
+
+		entry, ok := previous[issue.GetNumber()]
+		if !ok || !entry.updatedAt.Equal(issue.GetUpdatedAt()) {
+			labeledAt, err := f.forge.GetLabeledAt(ctx, owner, name, issue.GetNumber(), f.markers.NeedMerge)
+			if errors.Is(err, forge.ErrNotFound) {
+				// keeps the time of the last update.
+				labeledAt = time.Time{}
+			} else if err != nil {
+				return fmt.Errorf("unable to get the time of the label %s of the pull request #%d: %w", f.markers.NeedMerge, issue.GetNumber(), err)
+			}
+
+			entry = labeledAtEntry{updatedAt: issue.GetUpdatedAt(), labeledAt: labeledAt}
 		}
 
-		if resp.NextPage == 0 {
-			break
+		entries[issue.GetNumber()] = entry
+
+		if !entry.labeledAt.IsZero() {
+			times[issue.GetNumber()] = entry.labeledAt
 		}
+	}
+
+	f.labeledAt.set(fullName, entries)
+
+	return nil
+}
 
-		searchOpts.Page = resp.NextPage
+// priorityOf gets the rank of the highest priority label of a pull request (0 is the highest), or the number of priority labels if none.
+func (f Finder) priorityOf(issue *github.Issue) int {
+	for rank, lbl := range f.priority.Labels {
+		if hasLabel(issue, lbl) {
+			return rank
+		}
 	}
 
-	log.Debug().Str("query", query).Int("count", count).Msg("search queries count")
+	return len(f.priority.Labels)
+}
+
+// GetCurrentPull gets the current pull request, and the reason of the choice.
+// priorities: ff > retry > in progress > need merge (in the order of Sort)
+func (f Finder) GetCurrentPull(ctx context.Context, issues []*github.Issue) (*github.Issue, string, error) {
+	ctx, span := tracing.Start(ctx, "Finder.GetCurrentPull")
 
-	return overview, nil
+	issue, reason, err := f.getCurrentPull(ctx, issues)
+	tracing.End(span, err)
+
+	return issue, reason, err
 }
 
-// GetCurrentPull gets the current pull request.
-// priorities: ff > retry > in progress > need merge
-func (f Finder) GetCurrentPull(ctx context.Context, issues []*github.Issue) (*github.Issue, error) {
+func (f Finder) getCurrentPull(ctx context.Context, issues []*github.Issue) (*github.Issue, string, error) {
 	if len(issues) == 0 {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	ff := findIssuesWithLabel(issues, f.markers.MergeMethodPrefix+conf.MergeMethodFastForward)
 	if len(ff) > 1 {
 		f.displayIssues(ff)
 
-		return nil, fmt.Errorf("multiple pull requests with the label %s", f.markers.MergeMethodPrefix+conf.MergeMethodFastForward)
+		return nil, "", fmt.Errorf("multiple pull requests with the label %s", f.markers.MergeMethodPrefix+conf.MergeMethodFastForward)
 	}
 
 	if len(ff) > 0 {
-		return ff[0], nil
+		return ff[0], ReasonFastForward, nil
 	}
 
 	inProgress := findIssuesWithLabel(issues, f.markers.MergeInProgress)
@@ -95,11 +226,15 @@ func (f Finder) GetCurrentPull(ctx context.Context, issues []*github.Issue) (*gi
 	if len(inProgress) == 0 {
 		f.displayIssues(issues)
 
-		return issues[0], nil
+		if f.priorityOf(issues[0]) < len(f.priority.Labels) {
+			return issues[0], ReasonPriority, nil
+		}
+
+		return issues[0], ReasonOldest, nil
 	}
 
 	if len(inProgress) > 2 {
-		return nil, fmt.Errorf("illegal state: multiple PR with the label: %s", f.markers.MergeInProgress)
+		return nil, "", fmt.Errorf("illegal state: multiple PR with the label: %s", f.markers.MergeInProgress)
 	}
 
 	if f.retry.Number > 0 {
@@ -119,17 +254,17 @@ func (f Finder) GetCurrentPull(ctx context.Context, issues []*github.Issue) (*gi
 				if time.Since(issue.GetUpdatedAt()) > f.retry.Interval {
 					logger.Debug().Msgf("Find PR updated at %v", issue.GetUpdatedAt())
 
-					return issue, nil
+					return issue, ReasonRetry, nil
 				}
 			}
 
-			return nil, nil
+			return nil, ReasonRetryInterval, nil
 		}
 	}
 
 	f.displayIssues(inProgress)
 
-	return inProgress[0], nil
+	return inProgress[0], ReasonInProgress, nil
 }
 
 func (f Finder) displayIssues(issues []*github.Issue) {
@@ -142,20 +277,22 @@ func findIssuesWithLabel(issues []*github.Issue, lbl string) []*github.Issue {
 	var result []*github.Issue
 
 	for _, issue := range issues {
-		for _, label := range issue.Labels {
-			if strings.EqualFold(label.GetName(), lbl) {
-				result = append(result, issue)
-			}
+		if hasLabel(issue, lbl) {
+			result = append(result, issue)
 		}
 	}
 
 	return result
 }
 
-func getFullName(repoURL string) string {
-	n := strings.Split(repoURL, "/")
+func hasLabel(issue *github.Issue, lbl string) bool {
+	for _, label := range issue.Labels {
+		if strings.EqualFold(label.GetName(), lbl) {
+			return true
+		}
+	}
 
-	return n[len(n)-2] + "/" + n[len(n)-1]
+	return false
 }
 
 // findLabelPrefix Find an issue with a specific label prefix.
@@ -1,26 +1,22 @@
 package search
 
-import "fmt"
+import "github.com/traefik/lobicornis/v2/pkg/forge"
 
 // Parameter search parameter.
-type Parameter func() string
+type Parameter func(criteria *forge.Criteria)
 
 // NoOp No operation parameter.
-func NoOp() string {
-	return ""
-}
+func NoOp(_ *forge.Criteria) {}
 
 // Cond apply conditionally some parameters.
 func Cond(apply bool, parameters ...Parameter) Parameter {
 	if apply {
-		return func() string {
-			var filter string
+		return func(criteria *forge.Criteria) {
 			for _, param := range parameters {
 				if param != nil {
-					filter += param()
+					param(criteria)
 				}
 			}
-			return filter
 		}
 	}
 
@@ -28,30 +24,27 @@ func Cond(apply bool, parameters ...Parameter) Parameter {
 }
 
 // WithReviewApproved add a search filter by review approved.
-func WithReviewApproved() string {
-	return " review:approved "
+func WithReviewApproved(criteria *forge.Criteria) {
+	criteria.ReviewApproved = true
 }
 
 // WithLabels add a search filter by labels.
 func WithLabels(labels ...string) Parameter {
-	var labelsFilter string
-	for _, lbl := range labels {
-		labelsFilter += fmt.Sprintf("label:%s ", lbl)
-	}
-
-	return func() string {
-		return " " + labelsFilter
+	return func(criteria *forge.Criteria) {
+		criteria.Labels = append(criteria.Labels, labels...)
 	}
 }
 
 // WithExcludedLabels add a search filter by unwanted labels.
 func WithExcludedLabels(labels ...string) Parameter {
-	var labelsFilter string
-	for _, lbl := range labels {
-		labelsFilter += fmt.Sprintf("-label:%s ", lbl)
+	return func(criteria *forge.Criteria) {
+		criteria.ExcludedLabels = append(criteria.ExcludedLabels, labels...)
 	}
+}
 
-	return func() string {
-		return " " + labelsFilter
+// WithRepository add a search filter by repository.
+func WithRepository(fullName string) Parameter {
+	return func(criteria *forge.Criteria) {
+		criteria.Repository = fullName
 	}
 }
@@ -2,6 +2,7 @@ package search
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,8 +10,41 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/traefik/lobicornis/v2/pkg/conf"
+	"github.com/traefik/lobicornis/v2/pkg/forge"
 )
 
+// labeledAtForge a forge with the times of the addition of the labels: the other methods panic.
+type labeledAtForge struct {
+	forge.Forge
+
+	labeledAt map[int]time.Time
+}
+
+func (f labeledAtForge) GetLabeledAt(_ context.Context, _, _ string, number int, _ string) (time.Time, error) {
+	labeledAt, ok := f.labeledAt[number]
+	if !ok {
+		return time.Time{}, forge.ErrNotFound
+	}
+
+	return labeledAt, nil
+}
+
+// countingForge counts the calls to the timelines.
+type countingForge struct {
+	labeledAtForge
+
+	mu    sync.Mutex
+	calls []int
+}
+
+func (f *countingForge) GetLabeledAt(ctx context.Context, owner, repo string, number int, label string) (time.Time, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, number)
+	f.mu.Unlock()
+
+	return f.labeledAtForge.GetLabeledAt(ctx, owner, repo, number, label)
+}
+
 func TestFinder_GetCurrentPull(t *testing.T) {
 	markers := conf.Markers{
 		LightReview:       "bot/light-review",
@@ -29,12 +63,13 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 		OnStatuses:  false,
 	}
 
-	finder := New(nil, markers, retry)
+	finder := New(nil, markers, retry, conf.Priority{})
 
 	testCases := []struct {
-		desc     string
-		issues   []*github.Issue
-		expected int
+		desc           string
+		issues         []*github.Issue
+		expected       int
+		expectedReason string
 	}{
 		{
 			desc:     "no pull request",
@@ -51,7 +86,8 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 					},
 				},
 			},
-			expected: 1,
+			expected:       1,
+			expectedReason: ReasonOldest,
 		},
 		{
 			desc: "take the most pull request",
@@ -69,7 +105,8 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 					},
 				},
 			},
-			expected: 2,
+			expected:       2,
+			expectedReason: ReasonOldest,
 		},
 		{
 			desc: "take the pull request with merge in progress",
@@ -88,7 +125,8 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 					},
 				},
 			},
-			expected: 1,
+			expected:       1,
+			expectedReason: ReasonInProgress,
 		},
 		{
 			desc: "take the pull request with ff merge method",
@@ -114,7 +152,8 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 					},
 				},
 			},
-			expected: 1,
+			expected:       1,
+			expectedReason: ReasonFastForward,
 		},
 		{
 			desc: "take the pull request with retry",
@@ -135,7 +174,8 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 					},
 				},
 			},
-			expected: 1,
+			expected:       1,
+			expectedReason: ReasonRetry,
 		},
 	}
 
@@ -144,10 +184,151 @@ func TestFinder_GetCurrentPull(t *testing.T) {
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
 
-			pr, err := finder.GetCurrentPull(context.Background(), test.issues)
+			pr, reason, err := finder.GetCurrentPull(context.Background(), test.issues)
 			require.NoError(t, err)
 
 			assert.Equal(t, test.expected, pr.GetNumber())
+			assert.Equal(t, test.expectedReason, reason)
 		})
 	}
 }
+
+func TestFinder_Sort(t *testing.T) {
+	markers := conf.Markers{
+		NeedMerge:       "status/3-needs-merge",
+		MergeInProgress: "status/4-merge-in-progress",
+	}
+
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	newIssue := func(number int, updatedAt time.Time, labels ...string) *github.Issue {
+		issue := &github.Issue{Number: github.Int(number), UpdatedAt: &updatedAt}
+		for _, lbl := range append([]string{"status/3-needs-merge"}, labels...) {
+			issue.Labels = append(issue.Labels, &github.Label{Name: github.String(lbl)})
+		}
+
+		return issue
+	}
+
+	frg := labeledAtForge{labeledAt: map[int]time.Time{
+		1: start.Add(3 * time.Hour),
+		2: start.Add(2 * time.Hour),
+		3: start.Add(time.Hour),
+	}}
+
+	testCases := []struct {
+		desc           string
+		priority       conf.Priority
+		expected       []int
+		expectedReason string
+	}{
+		{
+			desc:           "order of the search",
+			expected:       []int{1, 2, 3, 4, 5},
+			expectedReason: ReasonOldest,
+		},
+		{
+			desc:           "priority labels",
+			priority:       conf.Priority{Labels: []string{"bot/priority-critical", "bot/priority-high"}},
+			expected:       []int{5, 2, 4, 1, 3},
+			expectedReason: ReasonPriority,
+		},
+		{
+			desc:           "time of the label",
+			priority:       conf.Priority{LabeledAt: true},
+			expected:       []int{4, 5, 3, 2, 1},
+			expectedReason: ReasonOldest,
+		},
+		{
+			desc:           "priority labels and time of the label",
+			priority:       conf.Priority{Labels: []string{"bot/priority-critical", "bot/priority-high"}, LabeledAt: true},
+			expected:       []int{5, 4, 2, 3, 1},
+			expectedReason: ReasonPriority,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// the order of the search: the least recently updated first.
+			// the pull requests 4 and 5 have no label event: the time of the last update is used.
+			issues := []*github.Issue{
+				newIssue(1, start),
+				newIssue(2, start.Add(time.Minute), "bot/priority-high"),
+				newIssue(3, start.Add(2*time.Minute)),
+				newIssue(4, start.Add(3*time.Minute), "bot/priority-high"),
+				newIssue(5, start.Add(4*time.Minute), "bot/priority-critical", "bot/priority-high"),
+			}
+
+			finder := New(frg, markers, conf.Retry{}, test.priority)
+
+			err := finder.Sort(context.Background(), "foo/bar", issues)
+			require.NoError(t, err)
+
+			var numbers []int
+			for _, issue := range issues {
+				numbers = append(numbers, issue.GetNumber())
+			}
+
+			assert.Equal(t, test.expected, numbers)
+
+			_, reason, err := finder.GetCurrentPull(context.Background(), issues)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedReason, reason)
+		})
+	}
+}
+
+func TestFinder_Sort_labeledAtCalls(t *testing.T) {
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	newIssue := func(number int, updatedAt time.Time, labels ...string) *github.Issue {
+		issue := &github.Issue{Number: github.Int(number), UpdatedAt: &updatedAt}
+		for _, lbl := range labels {
+			issue.Labels = append(issue.Labels, &github.Label{Name: github.String(lbl)})
+		}
+
+		return issue
+	}
+
+	frg := &countingForge{labeledAtForge: labeledAtForge{labeledAt: map[int]time.Time{
+		1: start.Add(-time.Hour),
+		2: start.Add(-2 * time.Hour),
+	}}}
+
+	priority := conf.Priority{Labels: []string{"bot/priority-high"}, LabeledAt: true}
+
+	finder := New(frg, conf.Markers{NeedMerge: "status/3-needs-merge"}, conf.Retry{}, priority)
+
+	issues := []*github.Issue{
+		newIssue(1, start),
+		newIssue(2, start.Add(time.Minute)),
+		newIssue(3, start.Add(2*time.Minute), "bot/priority-high"),
+	}
+
+	err := finder.Sort(context.Background(), "foo/bar", issues)
+	require.NoError(t, err)
+
+	// the pull request 3 is alone with its priority: its order doesn't depend on the time of the label.
+	assert.Equal(t, []int{1, 2}, frg.calls)
+	assert.Equal(t, 3, issues[0].GetNumber())
+	assert.Equal(t, 2, issues[1].GetNumber())
+
+	// the next sweep: only the updated pull requests are fetched.
+	frg.calls = nil
+
+	issues = []*github.Issue{
+		newIssue(1, start),
+		newIssue(2, start.Add(time.Hour)),
+		newIssue(3, start.Add(2*time.Minute), "bot/priority-high"),
+	}
+
+	err = finder.Sort(context.Background(), "foo/bar", issues)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2}, frg.calls)
+	assert.Equal(t, []int{3, 2, 1}, []int{issues[0].GetNumber(), issues[1].GetNumber(), issues[2].GetNumber()})
+}